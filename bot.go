@@ -1,10 +1,13 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
 	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/service"
 	"github.com/diamondburned/arikawa/v2/bot"
 	"github.com/diamondburned/arikawa/v2/gateway"
 )
@@ -13,33 +16,143 @@ type Bot struct {
 	Ctx *bot.Context
 }
 
+// errIfNotModerator returns an error if the author of the message is not allowed to moderate.
+func errIfNotModerator(msg *gateway.MessageCreateEvent) error {
+	if config.Discord() == nil || !config.Discord().IsModerator(msg.Member) {
+		return errors.New("you are not allowed to execute this command")
+	}
+	return nil
+}
+
 func (b *Bot) Modules(msg *gateway.MessageCreateEvent) (string, error) {
 	return strings.Join(config.EnabledModules(), "\n"), nil
 }
 
 // Link established a link between a discord channel and a target econ address that can or cannot be correct
 // in case th econ address matches any known addresses, its events are then logged to that specific discord channel.
-func (b *Bot) Link(msg *gateway.MessageCreateEvent, econAddr string) (string, error) {
+// The target may either be an econ address or a server alias.
+// Optionally only specific event categories (chat, vote, join, map) are logged to the channel.
+// A channel may be linked to multiple servers and a server may be linked to multiple channels.
+func (b *Bot) Link(msg *gateway.MessageCreateEvent, target string, categories ...string) (string, error) {
 	if err := config.Modules().ErrIfDiscordLoggingDisabled(); err != nil {
 		return "", err
 	}
-	err := config.Discord().AddLink(econAddr, msg.ChannelID)
+	if err := errIfNotModerator(msg); err != nil {
+		return "", err
+	}
+	econAddr, err := config.Servers().ResolveServer(target)
+	if err != nil {
+		return "", fmt.Errorf("failed to establish link between this channel and %s: %s", target, err)
+	}
+	err = config.Discord().AddLink(econAddr, msg.ChannelID, categories...)
 	if err != nil {
 		return "", fmt.Errorf("failed to establish link between this channel and %s: %s", econAddr, err)
 	}
+	if len(categories) > 0 {
+		return fmt.Sprintf("established connection between this channel and %s for %s", econAddr, strings.Join(categories, ", ")), nil
+	}
 	return fmt.Sprintf("established connection between this channel and %s", econAddr), nil
 }
 
-// Unlink removes the link between the current channel and its connected econ address.
-// no more messages from that address are received anymore.
+// Unlink removes the links between the current channel and its connected econ addresses.
+// no more messages from those addresses are received anymore.
 func (b *Bot) Unlink(msg *gateway.MessageCreateEvent) (string, error) {
 	if err := config.Modules().ErrIfDiscordLoggingDisabled(); err != nil {
 		return "", err
 	}
-	addr, err := config.Discord().RemoveChannelLink(msg.ChannelID)
+	if err := errIfNotModerator(msg); err != nil {
+		return "", err
+	}
+	addrs, err := config.Discord().RemoveChannelLink(msg.ChannelID)
 	if err != nil {
 		return "", fmt.Errorf("failed to unlink channel: %s", err)
 	}
 
-	return fmt.Sprintf("unlinked this channel from address %s", addr), nil
+	return fmt.Sprintf("unlinked this channel from %s", strings.Join(addrs, ", ")), nil
+}
+
+// Broadcast publishes the passed econ command to all servers that are connected to the broker
+// and reports which servers acknowledged it.
+func (b *Bot) Broadcast(msg *gateway.MessageCreateEvent, command bot.RawArguments) error {
+	if err := config.Modules().ErrIfDiscordLoggingDisabled(); err != nil {
+		return err
+	}
+	service.Broadcast(*msg, string(command))
+	return nil
+}
+
+// Exec executes the passed econ command on the servers of the target alias, group or econ address.
+func (b *Bot) Exec(msg *gateway.MessageCreateEvent, args bot.RawArguments) error {
+	if err := config.Modules().ErrIfDiscordLoggingDisabled(); err != nil {
+		return err
+	}
+	target, command := splitFirst(string(args))
+	if target == "" || command == "" {
+		return errors.New("usage: !exec <target> <command>")
+	}
+	service.Exec(*msg, target, command)
+	return nil
+}
+
+// Say relays the message into the in-game chat of the channel's linked server,
+// prefixed with the display name of the author.
+func (b *Bot) Say(msg *gateway.MessageCreateEvent, text bot.RawArguments) error {
+	if err := config.Modules().ErrIfDiscordLoggingDisabled(); err != nil {
+		return err
+	}
+	if err := errIfNotModerator(msg); err != nil {
+		return err
+	}
+	// fail early if the channel is not linked to exactly one server
+	if _, err := config.Discord().GetEconAddr(msg.ChannelID); err != nil {
+		return err
+	}
+
+	author := msg.Author.Username
+	if msg.Member != nil && msg.Member.Nick != "" {
+		author = msg.Member.Nick
+	}
+	command, err := config.Say().Command(msg.Author.ID, author, string(text))
+	if err != nil {
+		return err
+	}
+	// an empty target executes the command silently on the linked server
+	service.Exec(*msg, "", command)
+	return nil
+}
+
+// splitFirst splits the first whitespace separated word from the rest of the arguments
+// without touching any quotes in the rest of the arguments.
+func splitFirst(args string) (first, rest string) {
+	args = strings.TrimSpace(args)
+	idx := strings.IndexAny(args, " \t\n")
+	if idx < 0 {
+		return args, ""
+	}
+	return args[:idx], strings.TrimSpace(args[idx:])
+}
+
+// Links lists all links between econ addresses and discord channels.
+func (b *Bot) Links(msg *gateway.MessageCreateEvent) (string, error) {
+	if err := config.Modules().ErrIfDiscordLoggingDisabled(); err != nil {
+		return "", err
+	}
+	links := config.Discord().Links()
+	if len(links) == 0 {
+		return "no links established", nil
+	}
+
+	lines := make([]string, 0, len(links))
+	for _, link := range links {
+		categories := "all"
+		if len(link.Categories) > 0 {
+			categories = strings.Join(link.Categories, ", ")
+		}
+		server := link.Address
+		if alias := config.Servers().Alias(link.Address); alias != link.Address {
+			server = fmt.Sprintf("%s (%s)", alias, link.Address)
+		}
+		lines = append(lines, fmt.Sprintf("%s -> %s: %s", markdown.Escape(server), link.ChannelID.Mention(), categories))
+	}
+	return strings.Join(lines, "\n"), nil
 }
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	hostnameRegex     = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?)*$`)
+	numericLabelRegex = regexp.MustCompile(`(^|\.)[0-9]+$`)
+)
+
+// NormalizeAddress validates an econ address and returns its normalized form.
+// Supported formats are <ipv4>:<port>, [<ipv6>]:<port> and <hostname>:<port>.
+// IP addresses are converted into their canonical form, hostnames are lowercased
+// and leading zeros are removed from the port.
+func NormalizeAddress(econAddr string) (string, error) {
+	host, portStr, err := net.SplitHostPort(strings.TrimSpace(econAddr))
+	if err != nil {
+		return "", fmt.Errorf("invalid address: %s", econAddr)
+	}
+
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return "", fmt.Errorf("invalid port in address: %s", econAddr)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		host = ip.String()
+	} else if len(host) <= 253 && hostnameRegex.MatchString(host) && !numericLabelRegex.MatchString(host) {
+		// the top level domain must not be numeric in order not to accept malformed ip addresses
+		host = strings.ToLower(host)
+	} else {
+		return "", fmt.Errorf("invalid host in address: %s", econAddr)
+	}
+	return net.JoinHostPort(host, strconv.FormatUint(port, 10)), nil
+}
+
+// QueueName returns the name of the queue that requests for the server need to be published to.
+// The queue of a server is named after its econ address exactly as it was configured in a link,
+// an alias or a group, normalized addresses are only used in order to look up servers.
+func QueueName(econAddr string) string {
+	if discordCfg != nil {
+		if queue, found := discordCfg.queueName(econAddr); found {
+			return queue
+		}
+	}
+	if serverCfg != nil {
+		if queue, found := serverCfg.queueName(econAddr); found {
+			return queue
+		}
+	}
+	return econAddr
+}
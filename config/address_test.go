@@ -0,0 +1,126 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	tests := []struct {
+		econAddr string
+		want     string
+		wantErr  bool
+	}{
+		{"127.0.0.1:8303", "127.0.0.1:8303", false},
+		{" 127.0.0.1:08303 ", "127.0.0.1:8303", false},
+		{"[::1]:8303", "[::1]:8303", false},
+		{"[0:0::1]:8303", "[::1]:8303", false},
+		{"[0000:0000:0000:0000:0000:0000:0000:0001]:8303", "[::1]:8303", false},
+		{"[::ffff:127.0.0.1]:8303", "127.0.0.1:8303", false},
+		{"Localhost:8303", "localhost:8303", false},
+		{"econ.Example.com:8303", "econ.example.com:8303", false},
+		{"::1:8303", "", true},
+		{"[::1]", "", true},
+		{"127.0.0.1", "", true},
+		{"127.0.0.1:0", "", true},
+		{"127.0.0.1:65536", "", true},
+		{"127.0.0.256:8303", "", true},
+		{"127.1:8303", "", true},
+		{"-host:8303", "", true},
+		{"host_name:8303", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.econAddr, func(t *testing.T) {
+			got, err := NormalizeAddress(tt.econAddr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeAddress(%q) error = %v, wantErr %v", tt.econAddr, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeAddress(%q) = %q, want %q", tt.econAddr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeAddressCollisions(t *testing.T) {
+	tests := []struct {
+		name    string
+		a       string
+		b       string
+		collide bool
+	}{
+		{"same IPv6 address", "[::1]:8303", "[0:0::1]:8303", true},
+		{"IPv4 mapped IPv6 address", "127.0.0.1:8303", "[::ffff:127.0.0.1]:8303", true},
+		{"hostname case", "localhost:8303", "LOCALHOST:8303", true},
+		{"leading zeros of the port", "127.0.0.1:8303", "127.0.0.1:08303", true},
+		// hostnames are not resolved, the server reports the address it was configured with
+		{"hostname and IP", "localhost:8303", "127.0.0.1:8303", false},
+		{"IPv6 loopback and IPv4 loopback", "[::1]:8303", "127.0.0.1:8303", false},
+		{"different ports", "[::1]:8303", "[::1]:8304", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NormalizeAddress(tt.a)
+			if err != nil {
+				t.Fatalf("NormalizeAddress(%q) unexpected error: %v", tt.a, err)
+			}
+			b, err := NormalizeAddress(tt.b)
+			if err != nil {
+				t.Fatalf("NormalizeAddress(%q) unexpected error: %v", tt.b, err)
+			}
+			if (a == b) != tt.collide {
+				t.Errorf("NormalizeAddress(%q) = %q, NormalizeAddress(%q) = %q, want collision %v", tt.a, a, tt.b, b, tt.collide)
+			}
+		})
+	}
+}
+
+func TestQueueName(t *testing.T) {
+	useStatePath(t)
+	discordCfg = &discordConfig{keyValueDelimiter: "->"}
+	serverCfg = newServerConfig()
+	defer func() {
+		discordCfg = nil
+		serverCfg = nil
+	}()
+
+	if err := discordCfg.AddLink("[0:0::1]:8303", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := serverCfg.SetAlias("ctf", "LOCALHOST:8304"); err != nil {
+		t.Fatal(err)
+	}
+	if err := serverCfg.AddToGroup("all", "ctf", "127.0.0.1:08305"); err != nil {
+		t.Fatal(err)
+	}
+
+	// lookups use the normalized addresses
+	addrs, err := serverCfg.Resolve("all")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"127.0.0.1:8305", "localhost:8304"}; !reflect.DeepEqual(addrs, want) {
+		t.Errorf("Resolve(all) = %q, want %q", addrs, want)
+	}
+
+	tests := []struct {
+		econAddr string
+		want     string
+	}{
+		{"[::1]:8303", "[0:0::1]:8303"},
+		{"[0:0::1]:8303", "[0:0::1]:8303"},
+		{"localhost:8304", "LOCALHOST:8304"},
+		{"127.0.0.1:8305", "127.0.0.1:08305"},
+		// hostnames and IPs are different servers
+		{"127.0.0.1:8304", "127.0.0.1:8304"},
+		{"[::1]:8304", "[::1]:8304"},
+		{"::1:8303", "::1:8303"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.econAddr, func(t *testing.T) {
+			if got := QueueName(tt.econAddr); got != tt.want {
+				t.Errorf("QueueName(%q) = %q, want %q", tt.econAddr, got, tt.want)
+			}
+		})
+	}
+}
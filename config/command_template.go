@@ -0,0 +1,287 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	tparse "text/template/parse"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/console"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	configo "github.com/jxsl13/simple-configo"
+)
+
+var (
+	// legacyVariables converts the variables of the former command templates
+	// into their text/template equivalent, {REASON} is converted by convertLegacy.
+	legacyVariables = strings.NewReplacer(
+		"{IP}", "{{.IP}}",
+		"{ID}", "{{.ID}}",
+		"{DURATION:MINUTES}", "{{.Minutes}}",
+		"{DURATION:SECONDS}", "{{.Seconds}}",
+	)
+
+	// untrustedFields are controlled by players or moderators
+	untrustedFields = []string{"Name", "Clan", "Reason"}
+	// injections are filled into the untrusted fields in order to verify that templates quote or escape them
+	injections = []string{"; injected", `"`, `"; injected; "`, "# injected", `injected\`}
+
+	commandFuncs = template.FuncMap{
+		"quote":  console.Quote,
+		"escape": console.Escape,
+		// the text is the last argument in order to support pipelines: {{.Name | truncate 16}}
+		"truncate": func(maxLength int, text string) string {
+			return console.Truncate(text, maxLength)
+		},
+	}
+
+	// sampleCommandData is used to validate command templates at parse time
+	sampleCommandData = newCommandData(dto.Player{
+		ID:      1,
+		Name:    "nameless tee",
+		Clan:    "clan",
+		Country: -1,
+		IP:      "127.0.0.1",
+	}, "127.0.0.1:8303", time.Hour, "reason")
+)
+
+// CommandData contains the variables of command templates.
+type CommandData struct {
+	IP      string
+	ID      int
+	Name    string
+	Clan    string
+	Country string
+	// Server is the econ address of the player's server, Alias its alias or the econ address
+	Server   string
+	Alias    string
+	Range    string
+	Reason   string
+	Duration time.Duration
+	Minutes  int64
+	Seconds  int64
+	Time     time.Time
+}
+
+func newCommandData(player dto.Player, server string, duration time.Duration, reason string) CommandData {
+	data := CommandData{
+		IP:       player.IP,
+		ID:       player.ID,
+		Name:     player.Name,
+		Clan:     player.Clan,
+		Country:  markdown.CountryCode(player.Country),
+		Server:   server,
+		Alias:    server,
+		Reason:   reason,
+		Duration: duration,
+		Minutes:  int64(duration / time.Minute),
+		Seconds:  int64(duration / time.Second),
+		Time:     time.Now(),
+	}
+	if server != "" && Servers() != nil {
+		data.Alias = Servers().Alias(server)
+	}
+	return data
+}
+
+// commandTemplate is a text/template command template, e.g. ban {{.IP}} {{.Minutes}} {{.Reason | quote}}.
+// The variables {IP}, {ID}, {DURATION:MINUTES}, {DURATION:SECONDS} and {REASON}
+// of the former templates are still supported.
+// The fields Name, Clan and Reason must be quoted or escaped, as they are controlled by players or moderators.
+type commandTemplate struct {
+	raw  string
+	tmpl *template.Template
+}
+
+// parseCommandTemplate parses and validates the template, so that broken templates fail at startup.
+func parseCommandTemplate(name, raw string) (*commandTemplate, error) {
+	tmpl, err := template.New(name).
+		Funcs(commandFuncs).
+		Option("missingkey=error").
+		Parse(convertLegacy(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	ct := &commandTemplate{raw: raw, tmpl: tmpl}
+	if err = ct.checkInjections(); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return ct, nil
+}
+
+// convertLegacy converts the variables of the former command templates, the reason
+// is quoted or escaped depending on whether the variable {REASON} is quoted.
+func convertLegacy(raw string) string {
+	raw = console.ReplaceFunc(raw, "{REASON}", func(inQuotes bool) string {
+		if inQuotes {
+			return "{{escape .Reason}}"
+		}
+		return "{{quote .Reason}}"
+	})
+	return legacyVariables.Replace(raw)
+}
+
+// checkInjections returns an error if an untrusted field can change the statements of the command,
+// i.e. if the template does not quote or escape it.
+func (ct *commandTemplate) checkInjections() error {
+	sample, err := ct.Execute(sampleCommandData)
+	if err != nil {
+		return err
+	}
+	want := statementNames(sample)
+
+	for _, field := range untrustedFields {
+		for _, injection := range injections {
+			data := sampleCommandData
+			switch field {
+			case "Name":
+				data.Name = injection
+			case "Clan":
+				data.Clan = injection
+			case "Reason":
+				data.Reason = injection
+			}
+			command, err := ct.Execute(data)
+			if err != nil {
+				return err
+			}
+			if got := statementNames(command); !equalStrings(got, want) {
+				return fmt.Errorf("%s must be quoted or escaped, e.g. {{quote .%s}}", field, field)
+			}
+		}
+	}
+	return nil
+}
+
+// statementNames returns the command names of the statements of the command line.
+func statementNames(command string) []string {
+	statements := console.Statements(command)
+	names := make([]string, 0, len(statements))
+	for _, statement := range statements {
+		fields := strings.Fields(statement)
+		if len(fields) == 0 {
+			names = append(names, "")
+			continue
+		}
+		names = append(names, fields[0])
+	}
+	return names
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// commandTemplateParser parses the option into a validated template.
+func commandTemplateParser(out **commandTemplate, name string) configo.ParserFunc {
+	return func(value string) error {
+		ct, err := parseCommandTemplate(name, value)
+		if err != nil {
+			return err
+		}
+		*out = ct
+		return nil
+	}
+}
+
+// commandTemplateUnparser returns the raw template of the option.
+func commandTemplateUnparser(in **commandTemplate) configo.UnparserFunc {
+	return func() (string, error) {
+		if *in == nil {
+			return "", nil
+		}
+		return (*in).raw, nil
+	}
+}
+
+func (ct *commandTemplate) String() string {
+	return ct.raw
+}
+
+// Execute fills the template with the data, the result is a single line.
+func (ct *commandTemplate) Execute(data CommandData) (string, error) {
+	var sb strings.Builder
+	if err := ct.tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	command := strings.TrimSpace(sb.String())
+	if strings.ContainsAny(command, "\r\n") {
+		return "", fmt.Errorf("the command must not contain line breaks: %s", command)
+	}
+	return command, nil
+}
+
+// Uses returns true if the template references the variable, e.g. ID or IP.
+// Templates that pass the whole data on, e.g. {{template "name" .}} or {{printf "%v" .}},
+// are considered to use every variable.
+func (ct *commandTemplate) Uses(variable string) bool {
+	return nodeUses(ct.tmpl.Tree.Root, variable)
+}
+
+func nodeUses(node tparse.Node, variable string) bool {
+	uses := func(nodes ...tparse.Node) bool {
+		for _, n := range nodes {
+			if nodeUses(n, variable) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch n := node.(type) {
+	case *tparse.ListNode:
+		if n == nil {
+			return false
+		}
+		return uses(n.Nodes...)
+	case *tparse.ActionNode:
+		return uses(n.Pipe)
+	case *tparse.PipeNode:
+		if n == nil {
+			return false
+		}
+		for _, cmd := range n.Cmds {
+			if uses(cmd) {
+				return true
+			}
+		}
+	case *tparse.CommandNode:
+		return uses(n.Args...)
+	case *tparse.DotNode:
+		// the whole data, which might be the data of the template or the value of
+		// a range or with block, in both cases the variable might be accessed.
+		return true
+	case *tparse.FieldNode:
+		return len(n.Ident) > 0 && n.Ident[0] == variable
+	case *tparse.VariableNode:
+		// $ is the data of the template, all other variables are assigned within the template,
+		// so their declarations are walked instead.
+		if len(n.Ident) == 0 || n.Ident[0] != "$" {
+			return false
+		}
+		return len(n.Ident) == 1 || n.Ident[1] == variable
+	case *tparse.ChainNode:
+		// e.g. (.).ID, the fields cannot be resolved without the value of the node
+		return uses(n.Node)
+	case *tparse.TemplateNode:
+		// the invoked template can only access the data that is passed to it
+		return uses(n.Pipe)
+	case *tparse.IfNode:
+		return uses(n.Pipe, n.List, n.ElseList)
+	case *tparse.RangeNode:
+		return uses(n.Pipe, n.List, n.ElseList)
+	case *tparse.WithNode:
+		return uses(n.Pipe, n.List, n.ElseList)
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+)
+
+func TestParseCommandTemplate(t *testing.T) {
+	data := newCommandData(dto.Player{ID: 3, Name: "tee", IP: "1.2.3.4"}, "", time.Hour, `vpn"; shutdown`)
+
+	tests := []struct {
+		name    string
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"legacy", "ban {IP} {DURATION:MINUTES} {REASON}", `ban 1.2.3.4 60 "vpn\"; shutdown"`, false},
+		{"legacy quoted reason", `ban {IP} {DURATION:SECONDS} "{REASON}"`, `ban 1.2.3.4 3600 "vpn\"; shutdown"`, false},
+		{"legacy reason within text", `kick {ID} "vpn: {REASON}"`, `kick 3 "vpn: vpn\"; shutdown"`, false},
+		{"quote", "kick {{.ID}} {{quote .Reason}}", `kick 3 "vpn\"; shutdown"`, false},
+		{"pipeline", "kick {{.ID}} {{.Reason | truncate 3 | quote}}", `kick 3 "vpn"`, false},
+		{"escape", `say "{{escape .Name}} was banned"`, `say "tee was banned"`, false},
+		{"unquoted reason", "kick {{.ID}} {{.Reason}}", "", true},
+		{"unquoted name", "say {{.Name}} was banned", "", true},
+		{"escaped but unquoted clan", "say {{escape .Clan}}", "", true},
+		{"raw within quotes", `say "{{.Name}}"; kick {{.ID}}`, "", true},
+		{"unknown field", "ban {{.Unknown}}", "", true},
+		{"syntax error", "ban {{.IP}", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct, err := parseCommandTemplate(tt.name, tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCommandTemplate(%q) error = %v, wantErr %v", tt.raw, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			got, err := ct.Execute(data)
+			if err != nil {
+				t.Fatalf("Execute() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Execute() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommandTemplateUses(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want bool
+	}{
+		{"field", "kick {{.ID}}", true},
+		{"legacy", "kick {ID}", true},
+		{"other field", "ban {{.IP}}", false},
+		{"pipeline", "kick {{.ID | printf \"%d\"}}", true},
+		{"function argument", "kick {{printf \"%d\" .ID}}", true},
+		{"root variable", "kick {{$.ID}}", true},
+		{"other root field", "ban {{$.IP}}", false},
+		{"root", "say {{printf \"%v\" $ | quote}}", true},
+		{"dot", "say {{printf \"%v\" . | quote}}", true},
+		{"assigned variable", "{{$data := .}}kick {{$data.ID}}", true},
+		{"assigned field", "{{$ip := .IP}}ban {{$ip}}", false},
+		{"chain", "kick {{(.).ID}}", true},
+		{"if", "{{if .ID}}kick{{end}}", true},
+		{"else", "{{if .IP}}ban{{else}}kick {{.ID}}{{end}}", true},
+		{"with", "{{with .IP}}ban {{.}}{{end}}", true},
+		{"template", `{{define "id"}}{{.}}{{end}}kick {{template "id" .ID}}`, true},
+		{"template with data", `{{define "ip"}}{{.IP}}{{end}}ban {{template "ip" .}}`, true},
+		{"template without data", `{{define "ip"}}{{.}}{{end}}ban {{template "ip" .IP}}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ct, err := parseCommandTemplate(tt.name, tt.raw)
+			if err != nil {
+				t.Fatalf("parseCommandTemplate(%q) unexpected error: %v", tt.raw, err)
+			}
+			if got := ct.Uses("ID"); got != tt.want {
+				t.Errorf("Uses(ID) of %q = %v, want %v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,8 +8,16 @@ import (
 var (
 	moduleCfg      *moduleConfig
 	brokerCfg      *brokerConfig
+	serverCfg      *serverConfig
 	discordCfg     *discordConfig
+	moderationCfg  *moderationConfig
+	macroCfg       *macroConfig
+	sayCfg         *sayConfig
+	redisCfg       *redisConfig
 	detectVPNCfg   *detectVPNConfig
+	historyCfg     *historyConfig
+	schedulerCfg   *schedulerConfig
+	voteGuardCfg   *voteGuardConfig
 	envFileKey              = "ENV_FILE"
 	enabledModules []Config = make([]Config, 0)
 )
@@ -18,14 +26,42 @@ func Broker() *brokerConfig {
 	return brokerCfg
 }
 
+func Servers() *serverConfig {
+	return serverCfg
+}
+
 func Discord() *discordConfig {
 	return discordCfg
 }
 
+func Moderation() *moderationConfig {
+	return moderationCfg
+}
+
+func Macros() *macroConfig {
+	return macroCfg
+}
+
+func Say() *sayConfig {
+	return sayCfg
+}
+
 func DetectVPN() *detectVPNConfig {
 	return detectVPNCfg
 }
 
+func History() *historyConfig {
+	return historyCfg
+}
+
+func Scheduler() *schedulerConfig {
+	return schedulerCfg
+}
+
+func VoteGuard() *voteGuardConfig {
+	return voteGuardCfg
+}
+
 func Modules() *moduleConfig {
 	return moduleCfg
 }
@@ -38,7 +74,9 @@ func EnabledModules() []string {
 	return names
 }
 
-func init() {
+// Init parses the configuration of the enabled modules from the env file or the environment
+// and connects to the broker. It must be called before any configuration is accessed.
+func Init() {
 	moduleCfg = &moduleConfig{}
 	err := parse(moduleCfg)
 	if err != nil {
@@ -48,9 +86,27 @@ func init() {
 	brokerCfg = &brokerConfig{}
 	enabledModules = append(enabledModules, brokerCfg)
 
+	serverCfg = newServerConfig()
+	enabledModules = append(enabledModules, serverCfg)
+
 	if moduleCfg.enabledDiscordLog {
 		discordCfg = newDiscordConfig()
 		enabledModules = append(enabledModules, discordCfg)
+
+		moderationCfg = &moderationConfig{}
+		enabledModules = append(enabledModules, moderationCfg)
+
+		macroCfg = newMacroConfig()
+		enabledModules = append(enabledModules, macroCfg)
+
+		sayCfg = newSayConfig()
+		enabledModules = append(enabledModules, sayCfg)
+	}
+
+	if moduleCfg.enabledVPNDetection || moduleCfg.enabledPlayerHistory {
+		// shared by the modules that use redis
+		redisCfg = &redisConfig{}
+		enabledModules = append(enabledModules, redisCfg)
 	}
 
 	if moduleCfg.enabledVPNDetection {
@@ -58,6 +114,21 @@ func init() {
 		enabledModules = append(enabledModules, detectVPNCfg)
 	}
 
+	if moduleCfg.enabledPlayerHistory {
+		historyCfg = &historyConfig{}
+		enabledModules = append(enabledModules, historyCfg)
+	}
+
+	if moduleCfg.enabledScheduler {
+		schedulerCfg = newSchedulerConfig()
+		enabledModules = append(enabledModules, schedulerCfg)
+	}
+
+	if moduleCfg.enabledVoteGuard {
+		voteGuardCfg = &voteGuardConfig{}
+		enabledModules = append(enabledModules, voteGuardCfg)
+	}
+
 	err = parse(enabledModules...)
 	if err != nil {
 		log.Fatalln(err)
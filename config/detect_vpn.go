@@ -1,10 +1,15 @@
 package config
 
 import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
-	"github.com/jxsl13/goripr"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/ipranges"
+	"github.com/diamondburned/arikawa/v2/discord"
 	configo "github.com/jxsl13/simple-configo"
 	"github.com/jxsl13/simple-configo/parsers"
 	"github.com/jxsl13/simple-configo/unparsers"
@@ -20,24 +25,61 @@ type detectVPNConfig struct {
 	dataPath        string
 	blacklistFolder string
 	whitelistFolder string
-	redisAddress    string
-	redisPassword   string
 	redisDatabase   int
-	rdb             *goripr.Client
+	rdb             *RangeDatabase
+	reloadInterval  time.Duration
+	blacklistURLs   []string
+	fetchInterval   time.Duration
+	channelStr      string
+	channel         discord.ChannelID
+	vpnProviderConfig
+
+	// whitelisted contains the ranges of all whitelists, it is updated by Reload
+	whitelisted      ipranges.Set
+	whitelistedValid bool
+	whitelistedMu    sync.RWMutex
 
 	// these below parameters are guarded
 	broadcastBans bool
-	banCommand    string
+	// broadcastTarget is an optional server group or alias, empty means all servers.
+	broadcastTarget string
+	banCommand      *commandTemplate
+	unbanCommand    *commandTemplate
+	kickCommand     *commandTemplate
+	// target -> action[/duration[/command]]
+	policyStrs map[string]string
+	policies   []VPNPolicy
+	// dryRun only reports detected VPN players on all servers or the servers of the dryRunTargets
+	dryRun        bool
+	dryRunTargets []string
 	banReason     string
 	banDuration   time.Duration
 	// only guards the above parameters
 	sync.RWMutex
 }
 
-func (dvc *detectVPNConfig) RDB() *goripr.Client {
+// RDB returns the database that contains the IPv4 and IPv6 ranges of VPNs
+func (dvc *detectVPNConfig) RDB() *RangeDatabase {
 	return dvc.rdb
 }
 
+// IsWhitelisted returns true if one of the whitelists contains the IP.
+func (dvc *detectVPNConfig) IsWhitelisted(ip string) bool {
+	dvc.whitelistedMu.RLock()
+	defer dvc.whitelistedMu.RUnlock()
+	return dvc.whitelisted.Contains(net.ParseIP(ip))
+}
+
+// ReloadInterval is the interval in which the blacklist and whitelist folders are checked for changes, 0 if disabled.
+func (dvc *detectVPNConfig) ReloadInterval() time.Duration {
+	return dvc.reloadInterval
+}
+
+// Channel is the discord channel that receives announcements of the VPN module, 0 if disabled.
+func (dvc *detectVPNConfig) Channel() discord.ChannelID {
+	return dvc.channel
+}
+
 func (dvc *detectVPNConfig) BroadcastBans() bool {
 	dvc.RLock()
 	defer dvc.RUnlock()
@@ -50,16 +92,40 @@ func (dvc *detectVPNConfig) SetBroadcastBans(value bool) {
 	dvc.broadcastBans = value
 }
 
+func (dvc *detectVPNConfig) BroadcastTarget() string {
+	dvc.RLock()
+	defer dvc.RUnlock()
+	return dvc.broadcastTarget
+}
+
+func (dvc *detectVPNConfig) SetBroadcastTarget(value string) {
+	dvc.Lock()
+	defer dvc.Unlock()
+	dvc.broadcastTarget = value
+}
+
 func (dvc *detectVPNConfig) BanCommand() string {
 	dvc.RLock()
 	defer dvc.RUnlock()
-	return dvc.banCommand
+	return dvc.banCommand.String()
 }
 
-func (dvc *detectVPNConfig) SetBanCommand(value string) {
+func (dvc *detectVPNConfig) SetBanCommand(value string) error {
+	banCommand, err := parseCommandTemplate("BAN_COMMAND", value)
+	if err != nil {
+		return err
+	}
 	dvc.Lock()
 	defer dvc.Unlock()
-	dvc.banCommand = value
+	dvc.banCommand = banCommand
+	return nil
+}
+
+// UnbanCommand lifts a ban that was issued because of a falsely detected VPN.
+func (dvc *detectVPNConfig) UnbanCommand() string {
+	dvc.RLock()
+	defer dvc.RUnlock()
+	return dvc.unbanCommand.String()
 }
 
 func (dvc *detectVPNConfig) BanReason() string {
@@ -93,28 +159,61 @@ func (dvc *detectVPNConfig) SetBanDuration(value string) error {
 
 // Initaliztion and closing
 func (dvc *detectVPNConfig) PostParse() error {
+	if dvc.broadcastTarget != "" {
+		if _, err := Servers().Resolve(dvc.broadcastTarget); err != nil {
+			return fmt.Errorf("invalid BROADCAST_BANS_TARGET: %w", err)
+		}
+	}
+
+	if err := dvc.postParsePolicies(); err != nil {
+		return err
+	}
+	if err := dvc.postParseDryRun(); err != nil {
+		return err
+	}
+
+	if dvc.channelStr != "" {
+		value, err := strconv.ParseUint(dvc.channelStr, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid VPN_CHANNEL: %s", dvc.channelStr)
+		}
+		dvc.channel = discord.ChannelID(value)
+	}
+
+	for _, rawURL := range dvc.blacklistURLs {
+		parsed, err := url.Parse(rawURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("invalid BLACKLIST_URLS entry, expected a HTTP(S) URL: %s", rawURL)
+		}
+	}
+	if len(dvc.blacklistURLs) > 0 && dvc.fetchInterval <= 0 {
+		return fmt.Errorf("BLACKLIST_FETCH_INTERVAL must be positive")
+	}
+
 	err := dvc.initFolderStructure()
 	if err != nil {
 		return err
 	}
-	err = dvc.updateRedisDatabase()
+
+	redisOptions := redisCfg.redisOptions(dvc.redisDatabase)
+	err = dvc.postParseProviders(redisOptions)
 	if err != nil {
 		return err
 	}
 
-	dvc.rdb, err = goripr.NewClient(goripr.Options{
-		Addr:     dvc.redisAddress,
-		Password: dvc.redisPassword,
-		DB:       dvc.redisDatabase,
-	})
+	// the database is needed in order to import the lists
+	dvc.rdb, err = newRangeDatabase(redisOptions)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return dvc.updateRedisDatabase()
 }
 
 func (dvc *detectVPNConfig) Close() error {
+	if dvc.cache != nil {
+		dvc.cache.Close()
+	}
 	return dvc.rdb.Close()
 }
 
@@ -124,20 +223,6 @@ func (dvc *detectVPNConfig) Name() string {
 
 func (dvc *detectVPNConfig) Options() configo.Options {
 	optionsList := configo.Options{
-		{
-			Key:             "REDIS_ADDRESS",
-			Mandatory:       true,
-			Description:     "The REDIS_ADDRESS must have the following format: <hostname/ip>:<port>",
-			DefaultValue:    "localhost:6379",
-			ParseFunction:   parsers.String(&dvc.redisAddress),
-			UnparseFunction: unparsers.String(&dvc.redisAddress),
-		},
-		{
-			Key:             "REDIS_PASSWORD",
-			Description:     "Pasword used for the redis database, can be left empty.",
-			ParseFunction:   parsers.String(&dvc.redisPassword),
-			UnparseFunction: unparsers.String(&dvc.redisPassword),
-		},
 		{
 			Key:             "REDIS_DB",
 			Description:     "Is one of the 16 [0:15] ditinct databases that redis offers.",
@@ -166,6 +251,32 @@ func (dvc *detectVPNConfig) Options() configo.Options {
 			ParseFunction:   parsers.Regex(&dvc.whitelistFolder, folderRegex, errFolderMsg),
 			UnparseFunction: unparsers.String(&dvc.whitelistFolder),
 		},
+		{
+			Key:             "VPN_RELOAD_INTERVAL",
+			Description:     "The interval in which the blacklist and whitelist folders are checked for added, changed or removed files, 0 disables the hot reload (e.g. 30s, 1m, 1h)",
+			DefaultValue:    "1m",
+			ParseFunction:   parsers.Duration(&dvc.reloadInterval),
+			UnparseFunction: unparsers.Duration(&dvc.reloadInterval),
+		},
+		{
+			Key:             "BLACKLIST_URLS",
+			Description:     "Comma separated list of HTTP(S) URLs of blacklists that are periodically downloaded into the BLACKLIST_FOLDER",
+			ParseFunction:   parsers.List(&dvc.blacklistURLs, &serverPairDelimiter),
+			UnparseFunction: unparsers.List(&dvc.blacklistURLs, &serverPairDelimiter),
+		},
+		{
+			Key:             "BLACKLIST_FETCH_INTERVAL",
+			Description:     "The interval in which the BLACKLIST_URLS are downloaded, unmodified blacklists are not downloaded again (e.g. 1h, 24h)",
+			DefaultValue:    "6h",
+			ParseFunction:   parsers.Duration(&dvc.fetchInterval),
+			UnparseFunction: unparsers.Duration(&dvc.fetchInterval),
+		},
+		{
+			Key:             "VPN_CHANNEL",
+			Description:     "Optional discord channel ID that receives announcements of the VPN module, e.g. reloads of the blacklists and whitelists.",
+			ParseFunction:   parsers.String(&dvc.channelStr),
+			UnparseFunction: unparsers.String(&dvc.channelStr),
+		},
 		{
 			Key:             "BAN_REASON",
 			Description:     "The default reason that is used when a specific ban range does not specify a reason with # comments",
@@ -187,14 +298,53 @@ func (dvc *detectVPNConfig) Options() configo.Options {
 			ParseFunction:   parsers.Bool(&dvc.broadcastBans),
 			UnparseFunction: unparsers.Bool(&dvc.broadcastBans),
 		},
+		{
+			Key:             "BROADCAST_BANS_TARGET",
+			Description:     "An optional server group or alias that bans are broadcasted to, all servers are targeted if empty.",
+			ParseFunction:   parsers.String(&dvc.broadcastTarget),
+			UnparseFunction: unparsers.String(&dvc.broadcastTarget),
+		},
 		{
 			Key:             "BAN_COMMAND",
-			Description:     "You may use the variables {IP}, {ID}, {DURATION:MINUTES}, {DURATION:SECONDS}, {REASON}",
+			Description:     "A text/template with the variables {{.IP}}, {{.ID}}, {{.Name}}, {{.Clan}}, {{.Country}}, {{.Server}}, {{.Alias}}, {{.Range}}, {{.Reason}}, {{.Duration}}, {{.Minutes}}, {{.Seconds}}, {{.Time}} and the functions quote, escape and truncate, e.g. {{.Reason | truncate 32 | quote}}. {{.Name}}, {{.Clan}} and {{.Reason}} must be quoted or escaped. The variables {IP}, {ID}, {DURATION:MINUTES}, {DURATION:SECONDS}, {REASON} are still supported.",
 			DefaultValue:    "ban {IP} {DURATION:MINUTES} {REASON}",
-			ParseFunction:   parsers.String(&dvc.banCommand),
-			UnparseFunction: unparsers.String(&dvc.banCommand),
+			ParseFunction:   commandTemplateParser(&dvc.banCommand, "BAN_COMMAND"),
+			UnparseFunction: commandTemplateUnparser(&dvc.banCommand),
+		},
+		{
+			Key:             "VPN_KICK_COMMAND",
+			Description:     "The command that is executed for detected VPN players on servers with a kick policy, you may use the same variables as in the BAN_COMMAND",
+			DefaultValue:    "kick {{.ID}} {{quote .Reason}}",
+			ParseFunction:   commandTemplateParser(&dvc.kickCommand, "VPN_KICK_COMMAND"),
+			UnparseFunction: commandTemplateUnparser(&dvc.kickCommand),
+		},
+		{
+			Key:             "VPN_POLICIES",
+			Description:     "Policies for detected VPN players per server alias, group or econ address: target->action[/duration[/reason[/broadcast[/command]]]],target2->action2 with the actions ban, kick and exempt, e.g. tournament->ban/8760h/VPNs are not allowed in tournaments/true,casual->kick,lan->exempt. An empty duration, broadcast or command falls back to BAN_DURATION, BROADCAST_BANS and BAN_COMMAND or VPN_KICK_COMMAND, an empty reason keeps the detected reason. Aliases and econ addresses take precedence over groups.",
+			ParseFunction:   optionalMap(&dvc.policyStrs, &serverPairDelimiter, &serverKeyValueDelimiter),
+			UnparseFunction: unparsers.Map(&dvc.policyStrs, &serverPairDelimiter, &serverKeyValueDelimiter),
+		},
+		{
+			Key:             "VPN_DRY_RUN",
+			Description:     "Only report detected VPN players in the VPN_CHANNEL and the audit log without banning them, can be toggled at runtime via !vpn dryrun",
+			DefaultValue:    "false",
+			ParseFunction:   parsers.Bool(&dvc.dryRun),
+			UnparseFunction: unparsers.Bool(&dvc.dryRun),
+		},
+		{
+			Key:             "VPN_DRY_RUN_SERVERS",
+			Description:     "Comma separated list of server aliases, groups or econ addresses on which detected VPN players are only reported",
+			ParseFunction:   parsers.List(&dvc.dryRunTargets, &serverPairDelimiter),
+			UnparseFunction: unparsers.List(&dvc.dryRunTargets, &serverPairDelimiter),
+		},
+		{
+			Key:             "UNBAN_COMMAND",
+			Description:     "The command that lifts the ban of a player that was whitelisted via discord, you may use the same variables as in the BAN_COMMAND",
+			DefaultValue:    "unban {IP}",
+			ParseFunction:   commandTemplateParser(&dvc.unbanCommand, "UNBAN_COMMAND"),
+			UnparseFunction: commandTemplateUnparser(&dvc.unbanCommand),
 		},
 	}
 
-	return optionsList
+	return append(optionsList, dvc.providerOptions()...)
 }
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+var dryRunStateName = "vpn_dry_run"
+
+// dryRunState is the persisted dry run configuration, which takes precedence over
+// VPN_DRY_RUN and VPN_DRY_RUN_SERVERS.
+type dryRunState struct {
+	Enabled bool     `json:"enabled"`
+	Targets []string `json:"targets"`
+}
+
+// postParseDryRun validates the dry run targets and loads the state, expects the lock to be held.
+func (dvc *detectVPNConfig) postParseDryRun() error {
+	state := dryRunState{}
+	found, err := loadState(dryRunStateName, &state)
+	if err != nil {
+		return err
+	}
+	if found {
+		dvc.dryRun = state.Enabled
+		dvc.dryRunTargets = state.Targets
+	}
+	for _, target := range dvc.dryRunTargets {
+		if _, err := Servers().Resolve(target); err != nil {
+			return fmt.Errorf("invalid VPN_DRY_RUN_SERVERS target: %w", err)
+		}
+	}
+	return nil
+}
+
+// setDryRunState persists the dry run configuration immediately and replaces the current one
+// only if it was persisted successfully, expects the lock to be held
+func (dvc *detectVPNConfig) setDryRunState(enabled bool, targets []string) error {
+	err := saveState(dryRunStateName, dryRunState{
+		Enabled: enabled,
+		Targets: targets,
+	})
+	if err != nil {
+		return err
+	}
+	dvc.dryRun = enabled
+	dvc.dryRunTargets = targets
+	return nil
+}
+
+// IsDryRun returns true if detected VPN players on the server must not be punished,
+// but only be reported.
+func (dvc *detectVPNConfig) IsDryRun(econAddr string) bool {
+	if normalized, err := NormalizeAddress(econAddr); err == nil {
+		econAddr = normalized
+	}
+
+	dvc.RLock()
+	defer dvc.RUnlock()
+	if dvc.dryRun {
+		return true
+	}
+	for _, target := range dvc.dryRunTargets {
+		addrs, err := Servers().Resolve(target)
+		if err == nil && contains(addrs, econAddr) {
+			return true
+		}
+	}
+	return false
+}
+
+// DryRun returns whether the dry run is enabled for all servers and the
+// server aliases, groups or econ addresses it is enabled for.
+func (dvc *detectVPNConfig) DryRun() (enabled bool, targets []string) {
+	dvc.RLock()
+	defer dvc.RUnlock()
+	targets = make([]string, len(dvc.dryRunTargets))
+	copy(targets, dvc.dryRunTargets)
+	return dvc.dryRun, targets
+}
+
+// SetDryRun enables or disables the dry run for all servers.
+func (dvc *detectVPNConfig) SetDryRun(enabled bool) error {
+	dvc.Lock()
+	defer dvc.Unlock()
+	return dvc.setDryRunState(enabled, dvc.dryRunTargets)
+}
+
+// SetServerDryRun enables or disables the dry run for a server alias, group or econ address.
+func (dvc *detectVPNConfig) SetServerDryRun(target string, enabled bool) error {
+	if _, err := Servers().Resolve(target); err != nil {
+		return err
+	}
+
+	dvc.Lock()
+	defer dvc.Unlock()
+	targets := make([]string, 0, len(dvc.dryRunTargets)+1)
+	for _, existing := range dvc.dryRunTargets {
+		if existing != target {
+			targets = append(targets, existing)
+		}
+	}
+	if enabled {
+		targets = append(targets, target)
+	} else if len(targets) == len(dvc.dryRunTargets) {
+		return fmt.Errorf("the dry run is not enabled for %s", target)
+	}
+	sort.Strings(targets)
+	return dvc.setDryRunState(dvc.dryRun, targets)
+}
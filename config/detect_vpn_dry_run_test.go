@@ -0,0 +1,67 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	useStatePath(t)
+	useServers(t,
+		map[string]string{"ctf": "127.0.0.1:8303", "dm": "127.0.0.1:8304"},
+		map[string]string{"fun": "dm 127.0.0.1:8305"},
+	)
+	dvc := &detectVPNConfig{}
+	if err := dvc.postParseDryRun(); err != nil {
+		t.Fatal(err)
+	}
+
+	servers := []string{"127.0.0.1:8303", "127.0.0.1:8304", "127.0.0.1:8305", "127.0.0.1:8306"}
+	tests := []struct {
+		name    string
+		toggle  func() error
+		wantErr bool
+		enabled bool
+		targets []string
+		// whether the dry run applies to the servers
+		dryRun []bool
+	}{
+		{"disabled", func() error { return nil }, false, false, []string{}, []bool{false, false, false, false}},
+		{"enable alias", func() error { return dvc.SetServerDryRun("ctf", true) }, false, false, []string{"ctf"}, []bool{true, false, false, false}},
+		{"enable group", func() error { return dvc.SetServerDryRun("fun", true) }, false, false, []string{"ctf", "fun"}, []bool{true, true, true, false}},
+		{"enable twice", func() error { return dvc.SetServerDryRun("fun", true) }, false, false, []string{"ctf", "fun"}, []bool{true, true, true, false}},
+		{"enable unknown", func() error { return dvc.SetServerDryRun("race", true) }, true, false, []string{"ctf", "fun"}, []bool{true, true, true, false}},
+		{"enable all", func() error { return dvc.SetDryRun(true) }, false, true, []string{"ctf", "fun"}, []bool{true, true, true, true}},
+		{"disable all", func() error { return dvc.SetDryRun(false) }, false, false, []string{"ctf", "fun"}, []bool{true, true, true, false}},
+		{"disable alias", func() error { return dvc.SetServerDryRun("ctf", false) }, false, false, []string{"fun"}, []bool{false, true, true, false}},
+		{"disable again", func() error { return dvc.SetServerDryRun("ctf", false) }, true, false, []string{"fun"}, []bool{false, true, true, false}},
+		{"enable address", func() error { return dvc.SetServerDryRun("127.0.0.1:8306", true) }, false, false, []string{"127.0.0.1:8306", "fun"}, []bool{false, true, true, true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.toggle()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			enabled, targets := dvc.DryRun()
+			if enabled != tt.enabled || !reflect.DeepEqual(targets, tt.targets) {
+				t.Errorf("DryRun() = %v %v, want %v %v", enabled, targets, tt.enabled, tt.targets)
+			}
+			for idx, econAddr := range servers {
+				if got := dvc.IsDryRun(econAddr); got != tt.dryRun[idx] {
+					t.Errorf("IsDryRun(%s) = %v, want %v", econAddr, got, tt.dryRun[idx])
+				}
+			}
+		})
+	}
+
+	// the persisted state takes precedence over the environment
+	reloaded := &detectVPNConfig{dryRun: true, dryRunTargets: []string{"ctf"}}
+	if err := reloaded.postParseDryRun(); err != nil {
+		t.Fatal(err)
+	}
+	enabled, targets := reloaded.DryRun()
+	if want := []string{"127.0.0.1:8306", "fun"}; enabled || !reflect.DeepEqual(targets, want) {
+		t.Errorf("DryRun() after reload = %v %v, want false %v", enabled, targets, want)
+	}
+}
@@ -9,177 +9,423 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/console"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/ipranges"
 	"github.com/go-redis/redis"
-	"github.com/jxsl13/goripr"
 )
 
 const (
-	lastModifiedKey = "________________LAST_MODIFIED________________"
+	lastModifiedKey     = "________________LAST_MODIFIED________________"
+	fileRangesKeyPrefix = "________________FILE_RANGES________________:"
+	// importVersionKey marks databases whose files were imported with their ranges being tracked
+	// and whose values contain the blacklist range in front of the reason
+	importVersionKey = "________________IMPORT_VERSION________________"
+	importVersion    = "3"
 )
 
 var (
-	// 1: range
+	// 1: IPv4 or IPv6 range
 	// 3: reason
-	splitRegex = regexp.MustCompile(`^\s*([0-9\.\-\/]+)\s*(#\s*(.*[^\s])\s*)?$`)
+	splitRegex = regexp.MustCompile(`^\s*([0-9a-fA-F\.:\-\/]+)\s*(#\s*(.*[^\s])\s*)?$`)
+
+	// only a single import may run at a time
+	importMu sync.Mutex
+
+	// all IPv4 and IPv6 addresses
+	everything = []ipranges.Range{mustParseRange("0.0.0.0/0"), mustParseRange("::/0")}
 )
 
-func (dvc *detectVPNConfig) initFolderStructure() error {
-	blacklistPath := path.Join(dvc.dataPath, dvc.blacklistFolder)
-	whitelistPath := path.Join(dvc.dataPath, dvc.whitelistFolder)
+// FileStats are the parse statistics of an imported blacklist or whitelist file.
+type FileStats struct {
+	Path      string
+	Whitelist bool
+	// Added is the number of ranges that were added to the file since the last import
+	Added int
+	// Removed is the number of ranges that were removed from the file since the last import
+	Removed int
+	// Invalid is the number of lines that could not be parsed
+	Invalid int
+}
+
+// ImportStats summarizes the changes of a reload of the blacklist and whitelist folders.
+type ImportStats struct {
+	Imported []FileStats
+	Removed  []string
+	// Unchanged is the number of files that were skipped
+	Unchanged int
+	// Rebuilt is true if the whole database was rebuilt, as the previous import was unknown
+	Rebuilt bool
+}
+
+// Changed returns true if any file was imported or removed.
+func (is ImportStats) Changed() bool {
+	return len(is.Imported) > 0 || len(is.Removed) > 0
+}
+
+func (dvc *detectVPNConfig) blacklistPath() string {
+	return path.Join(dvc.dataPath, dvc.blacklistFolder)
+}
+
+func (dvc *detectVPNConfig) whitelistPath() string {
+	return path.Join(dvc.dataPath, dvc.whitelistFolder)
+}
 
-	err := os.MkdirAll(blacklistPath, 0666)
+func (dvc *detectVPNConfig) initFolderStructure() error {
+	err := os.MkdirAll(dvc.blacklistPath(), 0755)
 	if err != nil {
 		return err
 	}
-	err = os.MkdirAll(whitelistPath, 0666)
+	err = os.MkdirAll(dvc.whitelistPath(), 0755)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-// Use this to add blacklist domains and remove whitelisted domains afterwards
+// Use this to add blacklist ranges and remove whitelisted ranges afterwards
 func (dvc *detectVPNConfig) updateRedisDatabase() error {
+	stats, err := dvc.Reload()
+	if err != nil {
+		return err
+	}
+	log.Printf("Imported %d files, removed %d files, skipped %d unchanged files\n", len(stats.Imported), len(stats.Removed), stats.Unchanged)
+	return nil
+}
 
-	// Redis client, used for initialization purposed only.
-	initRdb := redis.NewClient(&redis.Options{
-		Addr:     dvc.redisAddress,
-		Password: dvc.redisPassword,
-		DB:       dvc.redisDatabase,
-	})
-	defer initRdb.Close()
-
-	blacklistPath := path.Join(dvc.dataPath, dvc.blacklistFolder)
-	whitelistPath := path.Join(dvc.dataPath, dvc.whitelistFolder)
-
-	err := filepath.Walk(blacklistPath, func(path string, info os.FileInfo, err error) error {
+// listFiles returns the modification times of all files within the folder
+func listFiles(folder string) (map[string]time.Time, error) {
+	files := make(map[string]time.Time)
+	err := filepath.Walk(folder, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if info.IsDir() {
 			return nil
 		}
-		dbLastModifiedStr, err := initRdb.HGet(lastModifiedKey, path).Result()
-		if err != nil && err != redis.Nil {
-			return err
+		files[path] = info.ModTime()
+		return nil
+	})
+	return files, err
+}
+
+// changedFiles returns the sorted files that were modified after the last time they were imported.
+func changedFiles(files map[string]time.Time, lastModified map[string]string) (changed []string) {
+	for path, modTime := range files {
+		lastModifiedStr, found := lastModified[path]
+		if !found {
+			changed = append(changed, path)
+			continue
 		}
-		// never touched before
-		if dbLastModifiedStr == "" {
-			fileLastModifiedStr := info.ModTime().Format(time.RFC3339)
-			_, err := initRdb.HSet(lastModifiedKey, path, fileLastModifiedStr).Result()
-			if err != nil {
-				return fmt.Errorf("failed to set last modified time in database for file: %s", path)
+		// we have already seen this file before
+		databaseLastModified, err := time.Parse(time.RFC3339Nano, lastModifiedStr)
+		if err != nil || modTime.After(databaseLastModified) {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Reload applies added, changed and removed blacklist and whitelist files to the range database,
+// so that it always contains the union of all blacklists minus the union of all whitelists.
+//
+// The ranges of every imported file are stored in redis, which allows to compute the
+// difference to the previous import of a file. Only the parts of the database that are
+// covered by added or removed ranges are updated, the rest of the database is not touched,
+// so that players are still checked against it while reloading.
+func (dvc *detectVPNConfig) Reload() (ImportStats, error) {
+	importMu.Lock()
+	defer importMu.Unlock()
+
+	// Redis client, used for the import bookkeeping only.
+	initRdb := redis.NewClient(redisCfg.redisOptions(dvc.redisDatabase))
+	defer initRdb.Close()
+
+	stats := ImportStats{}
+	blacklists, err := listFiles(dvc.blacklistPath())
+	if err != nil {
+		return stats, err
+	}
+	whitelists, err := listFiles(dvc.whitelistPath())
+	if err != nil {
+		return stats, err
+	}
+	lastModified, err := initRdb.HGetAll(lastModifiedKey).Result()
+	if err != nil && err != redis.Nil {
+		return stats, err
+	}
+
+	version, err := initRdb.Get(importVersionKey).Result()
+	if err != nil && err != redis.Nil {
+		return stats, err
+	}
+	resetBookkeeping := version != importVersion
+	if resetBookkeeping {
+		// the ranges of previously imported files are unknown, everything is imported again
+		lastModified = make(map[string]string)
+	}
+
+	// added and removed ranges of all files, these parts of the database need to be updated
+	changed := make([]ipranges.Range, 0)
+	addChanged := func(ranges map[string]string) {
+		for ipRange := range ranges {
+			if r, err := ipranges.Parse(ipRange); err == nil {
+				changed = append(changed, r)
 			}
-			return dvc.addIPsToDatabase(path)
 		}
-		// we have already seen this file before
-		databaseLastModified, err := time.Parse(time.RFC3339, dbLastModifiedStr)
+	}
+
+	for path := range lastModified {
+		_, isBlacklist := blacklists[path]
+		_, isWhitelist := whitelists[path]
+		if isBlacklist || isWhitelist {
+			continue
+		}
+		stats.Removed = append(stats.Removed, path)
+		previous, err := loadFileRanges(initRdb, path)
 		if err != nil {
-			return err
+			return stats, err
 		}
+		addChanged(previous)
+	}
+	sort.Strings(stats.Removed)
 
-		fileLastModified := info.ModTime()
+	changedBlacklists := changedFiles(blacklists, lastModified)
+	changedWhitelists := changedFiles(whitelists, lastModified)
+	stats.Unchanged = len(blacklists) + len(whitelists) - len(changedBlacklists) - len(changedWhitelists)
 
-		// file has not been modified after the last time we saw it
-		if !fileLastModified.After(databaseLastModified) {
-			log.Printf("File has not been modified, skipping: %s\n", path)
-			return nil
-		}
+	// the new ranges of the changed files
+	current := make(map[string]map[string]string, len(changedBlacklists)+len(changedWhitelists))
+	modified := make(map[string]time.Time, len(changedBlacklists)+len(changedWhitelists))
 
-		// file has been modified so we need to update the database
-		fileLastModifiedStr := fileLastModified.Format(time.RFC3339)
-		_, err = initRdb.HSet(lastModifiedKey, path, fileLastModifiedStr).Result()
+	for _, path := range append(changedBlacklists, changedWhitelists...) {
+		modTime, isWhitelist := whitelists[path]
+		if !isWhitelist {
+			modTime = blacklists[path]
+		}
+		ranges, invalid, err := dvc.parseFile(path)
 		if err != nil {
-			return fmt.Errorf("failed to update last modified state of file in database: %s", path)
+			return stats, err
 		}
-		return dvc.addIPsToDatabase(path)
-	})
-	if err != nil {
-		return err
+		previous, err := loadFileRanges(initRdb, path)
+		if err != nil {
+			return stats, err
+		}
+		added, removed := ipranges.Diff(previous, ranges)
+		addChanged(added)
+		addChanged(removed)
+		current[path] = ranges
+		modified[path] = modTime
+
+		stats.Imported = append(stats.Imported, FileStats{
+			Path:      path,
+			Whitelist: isWhitelist,
+			Added:     len(added),
+			Removed:   len(removed),
+			Invalid:   invalid,
+		})
 	}
 
-	err = filepath.Walk(whitelistPath, func(path string, info os.FileInfo, err error) error {
+	regions := ipranges.NewSet(changed)
+	if resetBookkeeping {
+		log.Println("Importing all blacklists and whitelists...")
+		stats.Rebuilt = true
+		regions = ipranges.NewSet(everything)
+	}
+	fileRanges := func(path string) (map[string]string, error) {
+		if ranges, found := current[path]; found {
+			return ranges, nil
+		}
+		return loadFileRanges(initRdb, path)
+	}
+	if regions.Len() > 0 {
+		err = dvc.update(regions, fileRanges, blacklists, whitelists)
 		if err != nil {
-			return err
+			return stats, err
 		}
-		if info.IsDir() {
-			return nil
+	}
+	// IPs of the whitelists are never looked up by any VPN provider
+	dvc.whitelistedMu.RLock()
+	updateWhitelisted := !dvc.whitelistedValid || stats.Changed()
+	dvc.whitelistedMu.RUnlock()
+	if updateWhitelisted {
+		whitelisted, err := clipFiles(ipranges.NewSet(everything), fileRanges, whitelists)
+		if err != nil {
+			return stats, err
 		}
+		ranges := make([]ipranges.Range, 0, len(whitelisted))
+		for _, entry := range whitelisted {
+			ranges = append(ranges, entry.Range)
+		}
+		dvc.whitelistedMu.Lock()
+		dvc.whitelisted, dvc.whitelistedValid = ipranges.NewSet(ranges), true
+		dvc.whitelistedMu.Unlock()
+	}
 
-		return dvc.removeIPsFromDatabase(path)
-	})
+	// the bookkeeping is only updated after the changes were applied successfully,
+	// otherwise the same changes are applied again by the next reload.
+	pipe := initRdb.TxPipeline()
+	if resetBookkeeping {
+		pipe.Del(lastModifiedKey)
+	}
+	for _, path := range stats.Removed {
+		pipe.HDel(lastModifiedKey, path)
+		pipe.Del(fileRangesKey(path))
+	}
+	for path, ranges := range current {
+		pipe.Del(fileRangesKey(path))
+		if len(ranges) > 0 {
+			fields := make(map[string]interface{}, len(ranges))
+			for ipRange, reason := range ranges {
+				fields[ipRange] = reason
+			}
+			pipe.HMSet(fileRangesKey(path), fields)
+		}
+		pipe.HSet(lastModifiedKey, path, modified[path].Format(time.RFC3339Nano))
+	}
+	pipe.Set(importVersionKey, importVersion, 0)
+	_, err = pipe.Exec()
 	if err != nil {
-		return err
+		return stats, fmt.Errorf("failed to update the imported files in database: %w", err)
 	}
-	return nil
+	return stats, nil
 }
 
-func parseLine(line string) (ipRange, reason string, err error) {
-	matches := splitRegex.FindStringSubmatch(line)
-	if len(matches) == 0 {
-		return "", "", errors.New("empty")
+// update recomputes the parts of the database that are covered by the regions.
+// The regions are removed, the overlapping parts of all blacklist ranges are added
+// and the overlapping parts of all whitelist ranges are removed afterwards.
+func (dvc *detectVPNConfig) update(regions ipranges.Set, fileRanges func(path string) (map[string]string, error), blacklists, whitelists map[string]time.Time) error {
+	// collect everything before touching the database, in order to keep the regions empty for as short as possible
+	added, err := clipFiles(regions, fileRanges, blacklists)
+	if err != nil {
+		return err
 	}
-	return strings.TrimSpace(matches[1]), strings.TrimSpace(matches[3]), nil
-}
-
-func (dvc *detectVPNConfig) addIPsToDatabase(filename string) error {
-	file, err := os.Open(filename)
+	whitelisted, err := clipFiles(regions, fileRanges, whitelists)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	cnt := 0
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		ip, reason, err := parseLine(scanner.Text())
+	for _, region := range regions.Ranges() {
+		err := dvc.rdb.Remove(region.String())
 		if err != nil {
-			continue
+			return fmt.Errorf("failed to remove range %s: %w", region, err)
 		}
-		if reason == "" {
-			reason = dvc.banReason
+	}
+	err = dvc.rdb.InsertBatch(added)
+	if err != nil {
+		return err
+	}
+	for _, entry := range whitelisted {
+		err := dvc.rdb.Remove(entry.String())
+		if err != nil {
+			return fmt.Errorf("failed to remove range %s: %w", entry.Range, err)
 		}
+	}
+	log.Printf("Updated %d regions: added %d blacklisted and removed %d whitelisted parts of IP ranges\n", regions.Len(), len(added), len(whitelisted))
+	return nil
+}
 
-		err = dvc.rdb.Insert(ip, reason)
-		cnt++
+// clipFiles returns the parts of the ranges of the files that are contained in the regions,
+// in the order in which they need to be applied. Every part keeps the range it was clipped from.
+func clipFiles(regions ipranges.Set, fileRanges func(path string) (map[string]string, error), files map[string]time.Time) ([]ipranges.Entry, error) {
+	entries := make([]ipranges.Entry, 0)
+	for _, path := range sortedPaths(files) {
+		ranges, err := fileRanges(path)
 		if err != nil {
-			if !errors.Is(err, goripr.ErrInvalidRange) {
-				log.Println(err, "Skipped invalid range:", ip)
+			return nil, err
+		}
+		for _, ipRange := range sortedRanges(ranges) {
+			r, err := ipranges.Parse(ipRange)
+			if err != nil {
+				continue
+			}
+			for _, part := range regions.Clip(r) {
+				entries = append(entries, ipranges.Entry{Range: part, Reason: joinSource(ipRange, ranges[ipRange])})
 			}
-			continue
 		}
 	}
-	log.Printf("Added %7d IP ranges from: %s", cnt, filename)
-	return nil
+	return entries, nil
+}
+
+func sortedPaths(files map[string]time.Time) []string {
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// fileRangesKey is the redis hash that contains the ranges of the last import of a file: range -> reason
+func fileRangesKey(path string) string {
+	return fileRangesKeyPrefix + path
+}
+
+func loadFileRanges(initRdb *redis.Client, path string) (map[string]string, error) {
+	ranges, err := initRdb.HGetAll(fileRangesKey(path)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+func mustParseRange(ipRange string) ipranges.Range {
+	r, err := ipranges.Parse(ipRange)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func parseLine(line string) (ipRange, reason string, err error) {
+	matches := splitRegex.FindStringSubmatch(line)
+	if len(matches) == 0 {
+		return "", "", errors.New("empty")
+	}
+	return strings.TrimSpace(matches[1]), strings.TrimSpace(matches[3]), nil
 }
 
-func (dvc *detectVPNConfig) removeIPsFromDatabase(filename string) error {
+// parseFile returns the valid ranges of the file with their reasons and the number of invalid lines.
+// Ranges without a reason get the default ban reason.
+func (dvc *detectVPNConfig) parseFile(filename string) (ranges map[string]string, invalid int, err error) {
 	file, err := os.Open(filename)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 	defer file.Close()
 
+	ranges = make(map[string]string)
 	scanner := bufio.NewScanner(file)
-	cnt := 0
 	for scanner.Scan() {
-		ip, _, err := parseLine(scanner.Text())
-		if err != nil {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
-
-		err = dvc.rdb.Remove(ip)
-		cnt++
-		if err != nil {
-			if !errors.Is(err, goripr.ErrInvalidRange) {
-				log.Println(err, "Skipped invalid range:", ip)
-			}
+		ipRange, reason, err := parseLine(line)
+		if err != nil || validateRange(ipRange) != nil {
+			invalid++
 			continue
 		}
+		// reasons end up in console commands and remote blacklists cannot be trusted
+		reason = console.Sanitize(reason)
+		if reason == "" {
+			reason = dvc.BanReason()
+		}
+		ranges[ipRange] = reason
 	}
-	log.Printf("Removed %5d potential IP ranges from: %s", cnt, filename)
-	return nil
+	return ranges, invalid, scanner.Err()
+}
+
+func sortedRanges(ranges map[string]string) []string {
+	result := make([]string, 0, len(ranges))
+	for ipRange := range ranges {
+		result = append(result, ipRange)
+	}
+	sort.Strings(result)
+	return result
 }
@@ -0,0 +1,203 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// managedFileName is the name of the blacklist and whitelist files that are modified via discord
+const managedFileName = "managed.txt"
+
+var (
+	// guards the managed files
+	managedMu sync.Mutex
+)
+
+// ListSource describes a blacklist or whitelist file.
+type ListSource struct {
+	Path      string
+	Whitelist bool
+	// Ranges is the number of ranges of the last import of the file
+	Ranges   int
+	Modified time.Time
+	// URL is set for remote blacklists, Path is empty if the remote blacklist was never downloaded
+	URL     string
+	Fetched time.Time
+}
+
+func (dvc *detectVPNConfig) managedBlacklistPath() string {
+	return filepath.Join(dvc.blacklistPath(), managedFileName)
+}
+
+func (dvc *detectVPNConfig) managedWhitelistPath() string {
+	return filepath.Join(dvc.whitelistPath(), managedFileName)
+}
+
+// AddRange adds the range to the managed blacklist and removes it from the managed whitelist.
+// The change needs to be applied via Reload afterwards.
+func (dvc *detectVPNConfig) AddRange(ipRange, reason string) error {
+	if err := validateRange(ipRange); err != nil {
+		return fmt.Errorf("invalid range %s: %w", ipRange, err)
+	}
+	reason = strings.Join(strings.Fields(reason), " ")
+
+	managedMu.Lock()
+	defer managedMu.Unlock()
+	if _, err := updateManagedFile(dvc.managedWhitelistPath(), ipRange, nil); err != nil {
+		return err
+	}
+	_, err := updateManagedFile(dvc.managedBlacklistPath(), ipRange, &reason)
+	return err
+}
+
+// RemoveRange removes the range from the managed blacklist, found is false if the
+// managed blacklist does not contain the range.
+// The change needs to be applied via Reload afterwards.
+func (dvc *detectVPNConfig) RemoveRange(ipRange string) (found bool, err error) {
+	managedMu.Lock()
+	defer managedMu.Unlock()
+	return updateManagedFile(dvc.managedBlacklistPath(), ipRange, nil)
+}
+
+// WhitelistRange adds the range to the managed whitelist and removes it from the managed blacklist.
+// The change needs to be applied via Reload afterwards.
+func (dvc *detectVPNConfig) WhitelistRange(ipRange string) error {
+	if err := validateRange(ipRange); err != nil {
+		return fmt.Errorf("invalid range %s: %w", ipRange, err)
+	}
+
+	managedMu.Lock()
+	defer managedMu.Unlock()
+	if _, err := updateManagedFile(dvc.managedBlacklistPath(), ipRange, nil); err != nil {
+		return err
+	}
+	empty := ""
+	_, err := updateManagedFile(dvc.managedWhitelistPath(), ipRange, &empty)
+	return err
+}
+
+// updateManagedFile removes all lines of the range from the file and appends the range
+// with its optional reason in case reason is not nil. Other lines are kept as they are.
+func updateManagedFile(filePath, ipRange string, reason *string) (found bool, err error) {
+	lines := make([]string, 0)
+	file, err := os.Open(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	} else if err == nil {
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if lineRange, _, err := parseLine(line); err == nil && lineRange == ipRange {
+				found = true
+				continue
+			}
+			lines = append(lines, line)
+		}
+		file.Close()
+		if err = scanner.Err(); err != nil {
+			return false, err
+		}
+	}
+
+	if reason == nil && !found {
+		return false, nil
+	}
+	if reason != nil {
+		line := ipRange
+		if *reason != "" {
+			line += " # " + *reason
+		}
+		lines = append(lines, line)
+	}
+	return found, writeFileAtomically(filePath, []byte(strings.Join(lines, "\n")+"\n"))
+}
+
+// writeFileAtomically replaces the file with a temporary file that contains the data.
+func writeFileAtomically(filePath string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".managed-*.tmp")
+	if err != nil {
+		return err
+	}
+	// noop after a successful rename
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	err = os.Chmod(tmp.Name(), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
+
+// ListSources returns the local blacklist and whitelist files and the remote blacklists.
+func (dvc *detectVPNConfig) ListSources() ([]ListSource, error) {
+	initRdb := redis.NewClient(redisCfg.redisOptions(dvc.redisDatabase))
+	defer initRdb.Close()
+
+	remoteSources := make(map[string]blacklistSource)
+	_, err := loadState(blacklistSourcesStateName, &remoteSources)
+	if err != nil {
+		return nil, err
+	}
+	remotePaths := make(map[string]string, len(remoteSources))
+	for rawURL, source := range remoteSources {
+		remotePaths[source.Path] = rawURL
+	}
+
+	result := make([]ListSource, 0)
+	for _, folder := range []string{dvc.blacklistPath(), dvc.whitelistPath()} {
+		files, err := listFiles(folder)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range sortedPaths(files) {
+			ranges, err := loadFileRanges(initRdb, path)
+			if err != nil {
+				return nil, err
+			}
+			source := ListSource{
+				Path:      path,
+				Whitelist: folder == dvc.whitelistPath(),
+				Ranges:    len(ranges),
+				Modified:  files[path],
+			}
+			if rawURL, found := remotePaths[path]; found {
+				source.URL = rawURL
+				source.Fetched = remoteSources[rawURL].Fetched
+			}
+			result = append(result, source)
+		}
+	}
+
+	// remote blacklists that were never downloaded successfully
+	missing := make([]string, 0)
+	for _, rawURL := range dvc.BlacklistURLs() {
+		source, found := remoteSources[rawURL]
+		if !found {
+			missing = append(missing, rawURL)
+			continue
+		}
+		if _, err := os.Stat(source.Path); os.IsNotExist(err) {
+			missing = append(missing, rawURL)
+		}
+	}
+	sort.Strings(missing)
+	for _, rawURL := range missing {
+		result = append(result, ListSource{URL: rawURL})
+	}
+	return result, nil
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// PolicyBan bans detected VPN players, the default
+	PolicyBan = "ban"
+	// PolicyKick only kicks detected VPN players
+	PolicyKick = "kick"
+	// PolicyExempt ignores detected VPN players
+	PolicyExempt = "exempt"
+)
+
+// VPNPolicy defines how detected VPN players are punished on the servers of the target.
+type VPNPolicy struct {
+	// Target is a server alias, group or econ address, empty for the global policy
+	Target   string
+	Action   string
+	Duration time.Duration
+	// Reason replaces the detected reason, empty in order to keep it
+	Reason string
+	// Broadcast is true if bans are executed on the other servers as well
+	Broadcast bool
+	// broadcast of a parsed policy, nil falls back to BROADCAST_BANS
+	broadcast *bool
+	command   *commandTemplate
+}
+
+// Broadcastable is true if the command may be executed on other servers than the one the player joined.
+func (p VPNPolicy) Broadcastable() bool {
+	return p.Broadcast && p.Action == PolicyBan && !p.command.Uses("ID")
+}
+
+// parseVPNPolicy parses a policy with the format target -> action[/duration[/reason[/broadcast[/command]]]]
+// an empty duration, reason, broadcast or command falls back to the global settings.
+func parseVPNPolicy(target, value string) (VPNPolicy, error) {
+	policy := VPNPolicy{
+		Target:   strings.TrimSpace(target),
+		Duration: -1,
+	}
+	if _, err := Servers().Resolve(policy.Target); err != nil {
+		return VPNPolicy{}, fmt.Errorf("invalid target of VPN policy %s: %w", target, err)
+	}
+
+	// the command is the last part, as it may contain slashes
+	parts := strings.SplitN(value, "/", 5)
+	policy.Action = strings.ToLower(strings.TrimSpace(parts[0]))
+	switch policy.Action {
+	case PolicyBan, PolicyKick, PolicyExempt:
+	default:
+		return VPNPolicy{}, fmt.Errorf("invalid action of VPN policy %s: %s, expected ban, kick or exempt", target, parts[0])
+	}
+	if len(parts) > 1 && strings.TrimSpace(parts[1]) != "" {
+		duration, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return VPNPolicy{}, fmt.Errorf("invalid duration of VPN policy %s: %w", target, err)
+		}
+		policy.Duration = duration
+	}
+	if len(parts) > 2 {
+		policy.Reason = strings.TrimSpace(parts[2])
+	}
+	if len(parts) > 3 && strings.TrimSpace(parts[3]) != "" {
+		broadcast, err := strconv.ParseBool(strings.TrimSpace(parts[3]))
+		if err != nil {
+			return VPNPolicy{}, fmt.Errorf("invalid broadcast value of VPN policy %s: %w", target, err)
+		}
+		policy.broadcast = &broadcast
+	}
+	if len(parts) > 4 && strings.TrimSpace(parts[4]) != "" {
+		command, err := parseCommandTemplate("command of VPN policy "+target, parts[4])
+		if err != nil {
+			return VPNPolicy{}, err
+		}
+		policy.command = command
+	}
+	return policy, nil
+}
+
+func (dvc *detectVPNConfig) postParsePolicies() error {
+	dvc.policies = make([]VPNPolicy, 0, len(dvc.policyStrs))
+	for target, value := range dvc.policyStrs {
+		policy, err := parseVPNPolicy(target, value)
+		if err != nil {
+			return err
+		}
+		dvc.policies = append(dvc.policies, policy)
+	}
+	sort.Slice(dvc.policies, func(i, j int) bool {
+		return dvc.policies[i].Target < dvc.policies[j].Target
+	})
+	return nil
+}
+
+// Policy returns the policy of the server with the global settings filled in.
+// Policies of the server's alias or econ address take precedence over policies
+// of its groups, which are applied in alphabetical order.
+func (dvc *detectVPNConfig) Policy(econAddr string) VPNPolicy {
+	if normalized, err := NormalizeAddress(econAddr); err == nil {
+		econAddr = normalized
+	}
+
+	dvc.RLock()
+	policies := dvc.policies
+	policy := VPNPolicy{
+		Action:    PolicyBan,
+		Duration:  dvc.banDuration,
+		Broadcast: dvc.broadcastBans,
+		command:   dvc.banCommand,
+	}
+	kickCommand := dvc.kickCommand
+	dvc.RUnlock()
+
+	var matched *VPNPolicy
+	for idx, candidate := range policies {
+		if addr, err := Servers().ResolveServer(candidate.Target); err == nil {
+			if addr == econAddr {
+				matched = &policies[idx]
+				break
+			}
+			continue
+		}
+		addrs, err := Servers().Resolve(candidate.Target)
+		if err == nil && matched == nil && contains(addrs, econAddr) {
+			matched = &policies[idx]
+		}
+	}
+	if matched == nil {
+		return policy
+	}
+
+	policy.Target = matched.Target
+	policy.Action = matched.Action
+	policy.Reason = matched.Reason
+	if matched.broadcast != nil {
+		policy.Broadcast = *matched.broadcast
+	}
+	if policy.Action == PolicyKick {
+		policy.command = kickCommand
+	}
+	if matched.Duration >= 0 {
+		policy.Duration = matched.Duration
+	}
+	if matched.command != nil {
+		policy.command = matched.command
+	}
+	return policy
+}
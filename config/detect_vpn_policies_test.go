@@ -0,0 +1,134 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// useServers replaces the server configuration of the test.
+func useServers(t *testing.T, aliases, groups map[string]string) {
+	t.Helper()
+	serverCfg = &serverConfig{aliases: aliases, groups: groups}
+	if err := serverCfg.PostParse(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		serverCfg = nil
+	})
+}
+
+func newTestPolicyConfig(t *testing.T, policyStrs map[string]string) *detectVPNConfig {
+	t.Helper()
+	banCommand, err := parseCommandTemplate("ban command", "ban {{.IP}} {{.Minutes}} {{quote .Reason}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kickCommand, err := parseCommandTemplate("kick command", "kick {{.ID}} {{quote .Reason}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dvc := &detectVPNConfig{
+		policyStrs:    policyStrs,
+		banDuration:   time.Hour,
+		broadcastBans: true,
+		banCommand:    banCommand,
+		kickCommand:   kickCommand,
+	}
+	if err := dvc.postParsePolicies(); err != nil {
+		t.Fatal(err)
+	}
+	return dvc
+}
+
+func TestParseVPNPolicy(t *testing.T) {
+	useStatePath(t)
+	useServers(t, map[string]string{"ctf": "127.0.0.1:8303"}, map[string]string{})
+
+	tests := []struct {
+		value    string
+		action   string
+		duration time.Duration
+		reason   string
+		wantErr  bool
+	}{
+		{"ban", PolicyBan, -1, "", false},
+		{" Kick ", PolicyKick, -1, "", false},
+		{"exempt", PolicyExempt, -1, "", false},
+		{"ban/2h", PolicyBan, 2 * time.Hour, "", false},
+		{"ban//vpn/false", PolicyBan, -1, "vpn", false},
+		{"ban/1h/vpn/true/ban {{.IP}} 5 {{quote .Reason}}", PolicyBan, time.Hour, "vpn", false},
+		{"slap", "", 0, "", true},
+		{"ban/forever", "", 0, "", true},
+		{"ban/1h/vpn/maybe", "", 0, "", true},
+		{"ban/1h/vpn/true/ban {{.IP}} 5 {{.Reason}}", "", 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseVPNPolicy("ctf", tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVPNPolicy(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Action != tt.action || got.Duration != tt.duration || got.Reason != tt.reason {
+				t.Errorf("parseVPNPolicy(%q) = %s %s %q, want %s %s %q", tt.value, got.Action, got.Duration, got.Reason, tt.action, tt.duration, tt.reason)
+			}
+		})
+	}
+
+	if _, err := parseVPNPolicy("unknown", "ban"); err == nil {
+		t.Error("parseVPNPolicy() expected an error for an unknown target")
+	}
+}
+
+func TestPolicyLookupOrder(t *testing.T) {
+	useStatePath(t)
+	useServers(t,
+		map[string]string{
+			"ctf":  "127.0.0.1:8303",
+			"dm":   "127.0.0.1:8304",
+			"race": "127.0.0.1:8305",
+		},
+		map[string]string{
+			"a_fun":    "ctf dm race",
+			"b_public": "ctf dm 127.0.0.1:8306",
+		},
+	)
+	dvc := newTestPolicyConfig(t, map[string]string{
+		"ctf":            "exempt",
+		"127.0.0.1:8304": "kick//vpn address",
+		"a_fun":          "ban/2h/vpn group a/false",
+		"b_public":       "kick/3h/vpn group b",
+	})
+
+	tests := []struct {
+		econAddr  string
+		target    string
+		action    string
+		duration  time.Duration
+		reason    string
+		broadcast bool
+	}{
+		// the alias beats the groups
+		{"127.0.0.1:8303", "ctf", PolicyExempt, time.Hour, "", true},
+		// the econ address beats the groups and is matched normalized
+		{"127.0.0.1:08304", "127.0.0.1:8304", PolicyKick, time.Hour, "vpn address", true},
+		// groups are applied in alphabetical order
+		{"127.0.0.1:8305", "a_fun", PolicyBan, 2 * time.Hour, "vpn group a", false},
+		{"127.0.0.1:8306", "b_public", PolicyKick, 3 * time.Hour, "vpn group b", true},
+		// servers without a policy use the global settings
+		{"127.0.0.1:8307", "", PolicyBan, time.Hour, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.econAddr, func(t *testing.T) {
+			got := dvc.Policy(tt.econAddr)
+			if got.Target != tt.target || got.Action != tt.action || got.Duration != tt.duration || got.Reason != tt.reason || got.Broadcast != tt.broadcast {
+				t.Errorf("Policy(%s) = %s %s %s %q %v, want %s %s %s %q %v", tt.econAddr,
+					got.Target, got.Action, got.Duration, got.Reason, got.Broadcast,
+					tt.target, tt.action, tt.duration, tt.reason, tt.broadcast,
+				)
+			}
+		})
+	}
+}
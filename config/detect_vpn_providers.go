@@ -0,0 +1,192 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	configo "github.com/jxsl13/simple-configo"
+	"github.com/jxsl13/simple-configo/parsers"
+	"github.com/jxsl13/simple-configo/unparsers"
+)
+
+const (
+	// ProviderRanges looks up IPs in the range database that is built from the blacklists and whitelists
+	ProviderRanges = "ranges"
+	// ProviderHTTP looks up IPs via a HTTP JSON API
+	ProviderHTTP = "http"
+
+	// PolicyAny detects a VPN if any provider detects a VPN
+	PolicyAny = "any"
+	// PolicyMajority detects a VPN if the majority of the answering providers detects a VPN
+	PolicyMajority = "majority"
+	// PolicyFirst uses the verdict of the first provider that answers
+	PolicyFirst = "first"
+)
+
+// HTTPProviderConfig configures a generic HTTP JSON API that checks IPs, e.g. proxycheck-style services.
+// Fields are referenced via dot separated paths that may contain the variable {IP}, e.g. {IP}.proxy
+type HTTPProviderConfig struct {
+	// URL contains the variable {IP}
+	URL             string
+	VerdictField    string
+	PositiveValues  []string
+	ReasonField     string
+	ConfidenceField string
+	// ConfidenceMax is the value of the confidence field that corresponds to a confidence of 1
+	ConfidenceMax float64
+	Timeout       time.Duration
+}
+
+// vpnProviderConfig is embedded in the detectVPNConfig
+type vpnProviderConfig struct {
+	providers []string
+	policy    string
+	cacheTTL  time.Duration
+	cache     *redis.Client
+
+	http                  HTTPProviderConfig
+	httpPositiveValuesStr string
+}
+
+func (vpc *vpnProviderConfig) postParseProviders(options *redis.Options) error {
+	if len(vpc.providers) == 0 {
+		return fmt.Errorf("VPN_PROVIDERS must not be empty")
+	}
+	for _, provider := range vpc.providers {
+		switch provider {
+		case ProviderRanges:
+		case ProviderHTTP:
+			if !strings.Contains(vpc.http.URL, "{IP}") {
+				return fmt.Errorf("VPN_HTTP_URL must contain the variable {IP}")
+			}
+			if _, err := url.Parse(vpc.http.URL); err != nil {
+				return fmt.Errorf("invalid VPN_HTTP_URL: %w", err)
+			}
+			if vpc.http.VerdictField == "" {
+				return fmt.Errorf("VPN_HTTP_VERDICT_FIELD must not be empty")
+			}
+		default:
+			return fmt.Errorf("unknown VPN provider: %s", provider)
+		}
+	}
+
+	switch vpc.policy {
+	case PolicyAny, PolicyMajority, PolicyFirst:
+	default:
+		return fmt.Errorf("unknown VPN_PROVIDER_POLICY: %s", vpc.policy)
+	}
+
+	vpc.http.PositiveValues = make([]string, 0)
+	for _, value := range strings.Split(vpc.httpPositiveValuesStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			vpc.http.PositiveValues = append(vpc.http.PositiveValues, strings.ToLower(value))
+		}
+	}
+
+	vpc.cache = redis.NewClient(options)
+	return nil
+}
+
+// Providers returns the names of the providers in the order in which they are consulted.
+func (vpc *vpnProviderConfig) Providers() []string {
+	result := make([]string, len(vpc.providers))
+	copy(result, vpc.providers)
+	return result
+}
+
+// ProviderPolicy combines the verdicts of the providers.
+func (vpc *vpnProviderConfig) ProviderPolicy() string {
+	return vpc.policy
+}
+
+// HTTPProvider returns the configuration of the HTTP JSON API provider.
+func (vpc *vpnProviderConfig) HTTPProvider() HTTPProviderConfig {
+	result := vpc.http
+	result.PositiveValues = append([]string(nil), vpc.http.PositiveValues...)
+	return result
+}
+
+// Cache is used to cache the verdicts of external providers.
+func (vpc *vpnProviderConfig) Cache() *redis.Client {
+	return vpc.cache
+}
+
+// CacheTTL is the time the verdicts of external providers are cached.
+func (vpc *vpnProviderConfig) CacheTTL() time.Duration {
+	return vpc.cacheTTL
+}
+
+func (vpc *vpnProviderConfig) providerOptions() configo.Options {
+	return configo.Options{
+		{
+			Key:             "VPN_PROVIDERS",
+			Description:     "Comma separated list of the providers that are consulted in order to detect VPNs: ranges (the blacklists and whitelists), http (VPN_HTTP_URL)",
+			DefaultValue:    ProviderRanges,
+			ParseFunction:   parsers.List(&vpc.providers, &serverPairDelimiter),
+			UnparseFunction: unparsers.List(&vpc.providers, &serverPairDelimiter),
+		},
+		{
+			Key:             "VPN_PROVIDER_POLICY",
+			Description:     "How the verdicts of multiple providers are combined: any (one provider detects a VPN), majority (most of the answering providers detect a VPN), first (the first answering provider decides)",
+			DefaultValue:    PolicyAny,
+			ParseFunction:   parsers.String(&vpc.policy),
+			UnparseFunction: unparsers.String(&vpc.policy),
+		},
+		{
+			Key:             "VPN_CACHE_TTL",
+			Description:     "The time the verdicts of external providers are cached in redis (e.g. 1h, 24h)",
+			DefaultValue:    "24h",
+			ParseFunction:   parsers.Duration(&vpc.cacheTTL),
+			UnparseFunction: unparsers.Duration(&vpc.cacheTTL),
+		},
+		{
+			Key:             "VPN_HTTP_URL",
+			Description:     "The URL of the http provider, {IP} is replaced with the IP of the player, e.g. https://proxycheck.io/v2/{IP}?vpn=1&risk=1",
+			ParseFunction:   parsers.String(&vpc.http.URL),
+			UnparseFunction: unparsers.String(&vpc.http.URL),
+		},
+		{
+			Key:             "VPN_HTTP_VERDICT_FIELD",
+			Description:     "Dot separated path of the JSON field that contains the verdict, may contain {IP}, e.g. {IP}.proxy",
+			DefaultValue:    "{IP}.proxy",
+			ParseFunction:   parsers.String(&vpc.http.VerdictField),
+			UnparseFunction: unparsers.String(&vpc.http.VerdictField),
+		},
+		{
+			Key:             "VPN_HTTP_POSITIVE_VALUES",
+			Description:     "Comma separated, case insensitive values of the verdict field that indicate a VPN",
+			DefaultValue:    "yes,true,1",
+			ParseFunction:   parsers.String(&vpc.httpPositiveValuesStr),
+			UnparseFunction: unparsers.String(&vpc.httpPositiveValuesStr),
+		},
+		{
+			Key:             "VPN_HTTP_REASON_FIELD",
+			Description:     "Optional dot separated path of the JSON field that contains the reason, e.g. {IP}.type",
+			ParseFunction:   parsers.String(&vpc.http.ReasonField),
+			UnparseFunction: unparsers.String(&vpc.http.ReasonField),
+		},
+		{
+			Key:             "VPN_HTTP_CONFIDENCE_FIELD",
+			Description:     "Optional dot separated path of the numeric JSON field that contains the confidence, e.g. {IP}.risk",
+			ParseFunction:   parsers.String(&vpc.http.ConfidenceField),
+			UnparseFunction: unparsers.String(&vpc.http.ConfidenceField),
+		},
+		{
+			Key:             "VPN_HTTP_CONFIDENCE_MAX",
+			Description:     "The value of the confidence field that corresponds to the highest confidence",
+			DefaultValue:    "100",
+			ParseFunction:   parsers.Float(&vpc.http.ConfidenceMax, 64),
+			UnparseFunction: unparsers.Float(&vpc.http.ConfidenceMax, 64),
+		},
+		{
+			Key:             "VPN_HTTP_TIMEOUT",
+			Description:     "The timeout of a request of the http provider (e.g. 2s, 10s)",
+			DefaultValue:    "5s",
+			ParseFunction:   parsers.Duration(&vpc.http.Timeout),
+			UnparseFunction: unparsers.Duration(&vpc.http.Timeout),
+		},
+	}
+}
@@ -1,8 +1,6 @@
 package config
 
 import (
-	"fmt"
-	"strings"
 	"time"
 
 	"github.com/Teeworlds-Server-Moderation/common/dto"
@@ -13,39 +11,135 @@ import (
 // serverTopic may be either the server's ip:port address or the broadcast topic
 const requestorID = "detect-vpn"
 
-func (dvc *detectVPNConfig) RequestBan(player dto.Player, banReason, sourceServerAddr string) error {
+// RequestBan bans or kicks the player whose IP is within the detected range according to
+// the policy of the player's server and returns the issued command.
+func (dvc *detectVPNConfig) RequestBan(policy VPNPolicy, player dto.Player, banReason, ipRange, sourceServerAddr string) (string, error) {
+	if policy.Action == PolicyExempt {
+		return "", nil
+	}
+
+	command, err := dvc.BuildBanCommand(policy, player, banReason, ipRange, sourceServerAddr)
+	if err != nil {
+		return "", err
+	}
+
+	// if the ban command contains an ID,
+	// it makes no sense to broadcast it
+	return command, dvc.publishCommand(command, requestorID, sourceServerAddr, policy.Broadcastable())
+}
+
+// BuildBanCommand constructs the command of the policy without executing it.
+func (dvc *detectVPNConfig) BuildBanCommand(policy VPNPolicy, player dto.Player, banReason, ipRange, sourceServerAddr string) (string, error) {
+	data := newCommandData(player, sourceServerAddr, policy.Duration, banReason)
+	data.Range = ipRange
+	return policy.command.Execute(data)
+}
+
+// RequestUnban lifts the ban of the player on the servers that the ban was issued on
+// and returns the issued unban command, which is empty if the server's policy does not ban.
+func (dvc *detectVPNConfig) RequestUnban(player dto.Player, sourceServerAddr, requestor string) (string, error) {
+	policy := dvc.Policy(sourceServerAddr)
+	if policy.Action != PolicyBan {
+		return "", nil
+	}
+
+	dvc.RLock()
+	template := dvc.unbanCommand
+	dvc.RUnlock()
+
+	reason := policy.Reason
+	if reason == "" {
+		reason = dvc.BanReason()
+	}
+	data := newCommandData(player, sourceServerAddr, policy.Duration, reason)
+	unbanCommand, err := template.Execute(data)
+	if err != nil {
+		return "", err
+	}
+
+	broadcast := policy.Broadcastable() && !template.Uses("ID")
+	return unbanCommand, dvc.publishCommand(unbanCommand, requestor, sourceServerAddr, broadcast)
+}
+
+// publishCommand executes the command on the source server or broadcasts it.
+func (dvc *detectVPNConfig) publishCommand(command, requestor, sourceServerAddr string, broadcast bool) error {
 	event := events.NewRequestCommandExecEvent()
 	event.Timestamp = time.Now().Format("2006-01-02 15:04:05")
-	event.Requestor = requestorID
+	event.Requestor = requestor
 	event.EventSource = requestorID
+	event.Command = command
+
+	if broadcast {
+		addrs, all, err := dvc.broadcastTargets(sourceServerAddr)
+		if err != nil {
+			return err
+		}
+		if all {
+			// ban on all servers
+			return Broker().Publisher().Publish(topics.Broadcast, "", event.Marshal())
+		}
+		for _, addr := range addrs {
+			err = Broker().Publisher().Publish("", QueueName(addr), event.Marshal())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// only ban on the server where the player joined
+	// do not publish to exchange, but directly to the queue
+	return Broker().Publisher().Publish("", QueueName(sourceServerAddr), event.Marshal())
+}
 
-	// construct command and replace
-	replacer := strings.NewReplacer(
-		"{IP}", player.IP,
-		"{ID}", fmt.Sprintf("%d", player.ID),
-		"{DURATION:MINUTES}", fmt.Sprintf("%d", int64(dvc.BanDuration()/time.Minute)),
-		"{DURATION:SECONDS}", fmt.Sprintf("%d", int64(dvc.BanDuration()/time.Second)),
-		"{REASON}", banReason,
-	)
-
-	broadcastFeasible := true
-	if strings.Contains(dvc.BanCommand(), "{ID}") {
-		broadcastFeasible = false
-	}
-
-	banCommand := replacer.Replace(dvc.BanCommand())
-	event.Command = banCommand
-
-	if dvc.BroadcastBans() && broadcastFeasible {
-		// ban on all servers
-		// if broadcasting makes sense
-		// if the ban command contains an ID,
-		// it makes no sense to broadcast it
-		Broker().Publisher().Publish(topics.Broadcast, "", event.Marshal())
+// broadcastTargets returns the servers of the BROADCAST_BANS_TARGET or all known servers
+// without the servers whose own policy does not ban detected VPN players or that are in dry run mode.
+// all is true if the command can be published to the broadcast exchange, which reaches every server,
+// as no server needs to be skipped. Otherwise only the source server, the linked servers and
+// the servers of aliases and groups are known.
+func (dvc *detectVPNConfig) broadcastTargets(sourceServerAddr string) (addrs []string, all bool, err error) {
+	target := dvc.BroadcastTarget()
+	if target != "" {
+		addrs, err = Servers().Resolve(target)
+		if err != nil {
+			return nil, false, err
+		}
 	} else {
-		// only ban on the server where the player joined
-		// do not publish to exchange, but directly to the queue
-		Broker().Publisher().Publish("", sourceServerAddr, event.Marshal())
+		if !dvc.hasBanExceptions() {
+			return nil, true, nil
+		}
+		addrs = append(Servers().Addresses(), sourceServerAddr)
+		if Discord() != nil {
+			for _, addr := range Discord().GetEconAddrs() {
+				if !contains(addrs, addr) {
+					addrs = append(addrs, addr)
+				}
+			}
+		}
+	}
+
+	result := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		if contains(result, addr) {
+			continue
+		}
+		if dvc.Policy(addr).Action == PolicyBan && !dvc.IsDryRun(addr) {
+			result = append(result, addr)
+		}
+	}
+	return result, false, nil
+}
+
+// hasBanExceptions returns true if any server does not ban detected VPN players.
+func (dvc *detectVPNConfig) hasBanExceptions() bool {
+	dvc.RLock()
+	defer dvc.RUnlock()
+	if len(dvc.dryRunTargets) > 0 {
+		return true
+	}
+	for _, policy := range dvc.policies {
+		if policy.Action != PolicyBan {
+			return true
+		}
 	}
-	return nil
+	return false
 }
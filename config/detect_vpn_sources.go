@@ -0,0 +1,205 @@
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	blacklistSourcesStateName = "blacklist_sources"
+	remoteFileNameRegex       = regexp.MustCompile(`[^a-zA-Z0-9\.\-_]+`)
+	// limits the file name length of remote blacklists
+	maxRemoteFileNameLength = 64
+	fetchClient             = &http.Client{Timeout: 2 * time.Minute}
+
+	// only a single fetch may run at a time
+	fetchMu sync.Mutex
+)
+
+// blacklistSource is the persisted caching information of a remote blacklist
+type blacklistSource struct {
+	Path         string    `json:"path"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Fetched      time.Time `json:"fetched"`
+}
+
+// FetchResult is the result of fetching a single remote blacklist.
+type FetchResult struct {
+	URL  string
+	Path string
+	// Updated is true if a new version was downloaded, false if it was not modified
+	Updated bool
+	Ranges  int
+	Err     error
+}
+
+// remoteBlacklistPath returns the path of the file within the blacklist folder
+// that contains the downloaded blacklist of the passed URL.
+func (dvc *detectVPNConfig) remoteBlacklistPath(rawURL string) string {
+	name := rawURL
+	if parsed, err := url.Parse(rawURL); err == nil {
+		name = parsed.Host + parsed.Path
+	}
+	name = remoteFileNameRegex.ReplaceAllString(name, "_")
+	if len(name) > maxRemoteFileNameLength {
+		name = name[len(name)-maxRemoteFileNameLength:]
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(rawURL))
+	return filepath.Join(dvc.blacklistPath(), fmt.Sprintf("remote-%s-%08x.txt", name, hash.Sum32()))
+}
+
+// BlacklistURLs returns the remote blacklists that are downloaded periodically.
+func (dvc *detectVPNConfig) BlacklistURLs() []string {
+	dvc.RLock()
+	defer dvc.RUnlock()
+	result := make([]string, len(dvc.blacklistURLs))
+	copy(result, dvc.blacklistURLs)
+	return result
+}
+
+// FetchInterval is the interval in which the remote blacklists are downloaded.
+func (dvc *detectVPNConfig) FetchInterval() time.Duration {
+	return dvc.fetchInterval
+}
+
+// FetchBlacklists downloads the remote blacklists into the blacklist folder, in case they were modified.
+// A failed download never replaces the last successfully downloaded version.
+// Files of remote blacklists that are not configured anymore are removed.
+// The files need to be imported via Reload afterwards.
+func (dvc *detectVPNConfig) FetchBlacklists() ([]FetchResult, error) {
+	fetchMu.Lock()
+	defer fetchMu.Unlock()
+
+	sources := make(map[string]blacklistSource)
+	_, err := loadState(blacklistSourcesStateName, &sources)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := dvc.BlacklistURLs()
+	results := make([]FetchResult, 0, len(urls))
+	for _, rawURL := range urls {
+		source := sources[rawURL]
+		result := dvc.fetchBlacklist(rawURL, &source)
+		if result.Err == nil {
+			sources[rawURL] = source
+		}
+		results = append(results, result)
+	}
+
+	for rawURL, source := range sources {
+		if contains(urls, rawURL) {
+			continue
+		}
+		err = os.Remove(source.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return results, err
+		}
+		delete(sources, rawURL)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].URL < results[j].URL
+	})
+	return results, saveState(blacklistSourcesStateName, sources)
+}
+
+// fetchBlacklist downloads a single blacklist and updates the caching information of the source.
+func (dvc *detectVPNConfig) fetchBlacklist(rawURL string, source *blacklistSource) (result FetchResult) {
+	result = FetchResult{
+		URL:  rawURL,
+		Path: dvc.remoteBlacklistPath(rawURL),
+	}
+
+	request, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if _, err := os.Stat(result.Path); err == nil {
+		// only use the cache information if the last good copy still exists
+		if source.ETag != "" {
+			request.Header.Set("If-None-Match", source.ETag)
+		}
+		if source.LastModified != "" {
+			request.Header.Set("If-Modified-Since", source.LastModified)
+		}
+	}
+
+	response, err := fetchClient.Do(request)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusNotModified:
+		source.Fetched = time.Now()
+		return result
+	case http.StatusOK:
+	default:
+		result.Err = fmt.Errorf("unexpected status: %s", response.Status)
+		return result
+	}
+
+	// the download is written to a temporary file outside of the blacklist folder,
+	// which is moved into the blacklist folder only if it contains valid ranges.
+	tmpFile, err := os.CreateTemp(dvc.dataPath, ".download-*")
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath)
+
+	_, err = io.Copy(tmpFile, response.Body)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("failed to download: %w", err)
+		return result
+	}
+
+	ranges, _, err := dvc.parseFile(tmpPath)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if len(ranges) == 0 {
+		result.Err = fmt.Errorf("the downloaded file does not contain any valid ranges")
+		return result
+	}
+	err = os.Chmod(tmpPath, 0644)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	err = os.Rename(tmpPath, result.Path)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	*source = blacklistSource{
+		Path:         result.Path,
+		ETag:         response.Header.Get("ETag"),
+		LastModified: response.Header.Get("Last-Modified"),
+		Fetched:      time.Now(),
+	}
+	result.Updated = true
+	result.Ranges = len(ranges)
+	return result
+}
@@ -2,25 +2,23 @@ package config
 
 import (
 	"fmt"
-	"regexp"
+	"path"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/console"
 	"github.com/diamondburned/arikawa/v2/discord"
 	configo "github.com/jxsl13/simple-configo"
 	"github.com/jxsl13/simple-configo/parsers"
 	"github.com/jxsl13/simple-configo/unparsers"
 )
 
-var (
-	addrRegex = regexp.MustCompile(`^(([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5])\.){3}([0-9]|[1-9][0-9]|1[0-9]{2}|2[0-4][0-9]|25[0-5]):(\d|[1-9]\d{1,3}|[1-5]\d{4}|6[0-4]\d{3}|65[0-4]\d{2}|655[0-2]\d|6553[0-5])$`)
-)
-
 func newDiscordConfig() *discordConfig {
 	config := &discordConfig{
-		addressToChannelStr: make(map[string]string),
-		addressToChannel:    make(map[string]discord.ChannelID),
-		channelToAddress:    make(map[discord.ChannelID]string),
+		linkStrs: make([]string, 0),
+		links:    make([]link, 0),
 	}
 
 	return config
@@ -29,12 +27,10 @@ func newDiscordConfig() *discordConfig {
 type discordConfig struct {
 	Token string
 
-	addressToChannelStr map[string]string
+	linkStrs []string
 
-	// addressToChannel maps econ addresses to a Discord channel ID
-	addressToChannel map[string]discord.ChannelID
-	// channelToAddress maps a discord channel to th ecorresponding econ address
-	channelToAddress map[discord.ChannelID]string
+	// links connect econ addresses and discord channels (many to many)
+	links []link
 
 	pairDelimiter     string
 	keyValueDelimiter string
@@ -42,6 +38,23 @@ type discordConfig struct {
 	skipJoinLeaveMessages bool
 	skipWhisperMessages   bool
 
+	// moderatorRoles contains the role IDs that are allowed to execute commands,
+	// an empty set allows everyone to execute commands.
+	moderatorRoles map[string]bool
+	// blockedCommands contains patterns of econ commands that must not be executed via discord.
+	blockedCommands []string
+
+	// adminChannels are allowed to see sensitive player data like IPs
+	adminChannels map[string]bool
+
+	auditChannelStr string
+	// auditChannel receives an audit trail of all executed commands, disabled if 0
+	auditChannel discord.ChannelID
+
+	// confirmCommands contains patterns of destructive econ commands that need to be confirmed
+	confirmCommands []string
+	confirmTimeout  time.Duration
+
 	sync.RWMutex
 }
 
@@ -49,128 +62,132 @@ func (dlc *discordConfig) PostParse() error {
 	dlc.Lock()
 	defer dlc.Unlock()
 
-	for addr, ch := range dlc.addressToChannelStr {
-		value, err := strconv.ParseUint(ch, 10, 64)
+	dlc.links = make([]link, 0, len(dlc.linkStrs))
+	for _, linkStr := range dlc.linkStrs {
+		l, err := parseLink(linkStr, dlc.keyValueDelimiter)
 		if err != nil {
-			return fmt.Errorf("invalid channel ID: %d for %s", value, addr)
+			return err
 		}
-		dlc.addressToChannel[addr] = discord.ChannelID(value)
-		dlc.channelToAddress[discord.ChannelID(value)] = addr
+		if dlc.indexOfLink(l.addr, l.channelID) >= 0 {
+			return fmt.Errorf("duplicate link: %s", linkStr)
+		}
+		dlc.links = append(dlc.links, l)
 	}
-	return nil
+	dlc.updateLinkStrs()
+
+	if dlc.auditChannelStr != "" {
+		value, err := strconv.ParseUint(dlc.auditChannelStr, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid audit channel ID: %s", dlc.auditChannelStr)
+		}
+		dlc.auditChannel = discord.ChannelID(value)
+	}
+	return dlc.loadLinks()
 }
 
 func (dlc *discordConfig) Close() error {
 	return nil
 }
 
-func (dlc *discordConfig) GetChannel(econAddr string) (discord.ChannelID, error) {
-	if !addrRegex.MatchString(econAddr) {
-		return 0, fmt.Errorf("invalid address: %s", econAddr)
-	}
-	dlc.RLock()
-	channelID, found := dlc.addressToChannel[econAddr]
-	dlc.RUnlock()
-	if !found {
-		return 0, fmt.Errorf("unknown econ address: %s", econAddr)
-	}
-	return channelID, nil
-}
-func (dlc *discordConfig) GetEconAddr(channelID discord.ChannelID) (string, error) {
-
+func (dlc *discordConfig) GetSkipJoinLeaveMessages() bool {
 	dlc.RLock()
-	addr, found := dlc.channelToAddress[channelID]
-	dlc.RUnlock()
-	if !found {
-		return "", fmt.Errorf("unknown channel id: %d", channelID)
-	}
-	return addr, nil
+	defer dlc.RUnlock()
+	return dlc.skipJoinLeaveMessages
 }
 
-func (dlc *discordConfig) AddLink(econAddr string, channelID discord.ChannelID) error {
-	if !addrRegex.MatchString(econAddr) {
-		return fmt.Errorf("invalid address: %s", econAddr)
-	}
-
+func (dlc *discordConfig) SetSkipJoinLeaveMessages(value bool) {
 	dlc.Lock()
 	defer dlc.Unlock()
-
-	_, found := dlc.addressToChannel[econAddr]
-	if found {
-		return fmt.Errorf("address link already exists %s", econAddr)
-	}
-
-	_, found = dlc.channelToAddress[channelID]
-	if found {
-		return fmt.Errorf("channel link already exists: %d", channelID)
-	}
-
-	// neither exist -> create a link
-	dlc.addressToChannel[econAddr] = channelID
-	dlc.addressToChannelStr[econAddr] = strconv.FormatUint(uint64(channelID), 10)
-	dlc.channelToAddress[channelID] = econAddr
-	return nil
+	dlc.skipJoinLeaveMessages = value
 }
 
-// RemoveAddressLink removes the link via its econ address key value
-func (dlc *discordConfig) RemoveAddressLink(econAddr string) (discord.ChannelID, error) {
-	if !addrRegex.MatchString(econAddr) {
-		return 0, fmt.Errorf("invalid address: %s", econAddr)
-	}
+func (dlc *discordConfig) GetSkipWhisperMessages() bool {
+	dlc.RLock()
+	defer dlc.RUnlock()
+	return dlc.skipWhisperMessages
+}
 
+func (dlc *discordConfig) SetSkipWhisperMessages(value bool) {
 	dlc.Lock()
 	defer dlc.Unlock()
+	dlc.skipWhisperMessages = value
+}
 
-	channel, found := dlc.addressToChannel[econAddr]
-	if !found {
-		return 0, fmt.Errorf("address not found %s", econAddr)
+// IsModerator returns true if the guild member is allowed to execute commands.
+func (dlc *discordConfig) IsModerator(member *discord.Member) bool {
+	dlc.RLock()
+	defer dlc.RUnlock()
+	if len(dlc.moderatorRoles) == 0 {
+		return true
 	}
-
-	delete(dlc.addressToChannelStr, econAddr)
-	delete(dlc.addressToChannel, econAddr)
-	delete(dlc.channelToAddress, channel)
-	return channel, nil
+	if member == nil {
+		return false
+	}
+	for _, role := range member.RoleIDs {
+		if dlc.moderatorRoles[role.String()] {
+			return true
+		}
+	}
+	return false
 }
 
-// RemoveChannelLink removes the channel via its channelID key
-func (dlc *discordConfig) RemoveChannelLink(channelID discord.ChannelID) (string, error) {
-
-	dlc.Lock()
-	defer dlc.Unlock()
-
-	addr, found := dlc.channelToAddress[channelID]
-	if !found {
-		return "", fmt.Errorf("channel not found %d", channelID)
+// CheckCommandPolicy returns an error if any of the ; separated statements of the command
+// matches a blocked command pattern.
+func (dlc *discordConfig) CheckCommandPolicy(command string) error {
+	dlc.RLock()
+	defer dlc.RUnlock()
+	if name, matched := matchCommand(dlc.blockedCommands, command); matched {
+		return fmt.Errorf("the command %s is not allowed to be executed via discord", name)
 	}
+	return nil
+}
 
-	delete(dlc.channelToAddress, channelID)
-	delete(dlc.addressToChannel, addr)
-	delete(dlc.addressToChannelStr, addr)
-	return addr, nil
+// RequiresConfirmation returns true if any of the ; separated statements of the command
+// matches a pattern of a destructive command.
+func (dlc *discordConfig) RequiresConfirmation(command string) bool {
+	dlc.RLock()
+	defer dlc.RUnlock()
+	_, matched := matchCommand(dlc.confirmCommands, command)
+	return matched
 }
 
-func (dlc *discordConfig) GetSkipJoinLeaveMessages() bool {
+// ConfirmTimeout is the time a destructive command may be confirmed.
+func (dlc *discordConfig) ConfirmTimeout() time.Duration {
 	dlc.RLock()
 	defer dlc.RUnlock()
-	return dlc.skipJoinLeaveMessages
+	return dlc.confirmTimeout
 }
 
-func (dlc *discordConfig) SetSkipJoinLeaveMessages(value bool) {
-	dlc.Lock()
-	defer dlc.Unlock()
-	dlc.skipJoinLeaveMessages = value
+// matchCommand returns the name of the first command of the ; separated statements
+// that matches any of the case insensitive glob patterns.
+func matchCommand(patterns []string, command string) (string, bool) {
+	for _, statement := range console.Statements(command) {
+		fields := strings.Fields(statement)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(strings.ToLower(pattern), name); matched {
+				return fields[0], true
+			}
+		}
+	}
+	return "", false
 }
 
-func (dlc *discordConfig) GetSkipWhisperMessages() bool {
+// IsAdminChannel returns true if sensitive player data like IPs may be shown in the channel.
+func (dlc *discordConfig) IsAdminChannel(channelID discord.ChannelID) bool {
 	dlc.RLock()
 	defer dlc.RUnlock()
-	return dlc.skipWhisperMessages
+	return dlc.adminChannels[channelID.String()]
 }
 
-func (dlc *discordConfig) SetSkipWhisperMessages(value bool) {
-	dlc.Lock()
-	defer dlc.Unlock()
-	dlc.skipWhisperMessages = value
+// AuditChannel returns the channel that receives the audit trail, 0 if disabled.
+func (dlc *discordConfig) AuditChannel() discord.ChannelID {
+	dlc.RLock()
+	defer dlc.RUnlock()
+	return dlc.auditChannel
 }
 
 func (dlc *discordConfig) Name() string {
@@ -202,10 +219,10 @@ func (dlc *discordConfig) Options() configo.Options {
 		},
 		{
 			Key:             "ADDRESS_CHANNEL_MAPPING",
-			Description:     "ip:econ_port->discord_channel_id,ip:econ_port2->",
+			Description:     "ip:econ_port->discord_channel_id[->chat+vote+join+map],ip:econ_port2->discord_channel_id2, all event categories are logged if none are specified",
 			Mandatory:       true,
-			ParseFunction:   parsers.Map(&dlc.addressToChannelStr, &dlc.pairDelimiter, &dlc.keyValueDelimiter),
-			UnparseFunction: unparsers.Map(&dlc.addressToChannelStr, &dlc.pairDelimiter, &dlc.keyValueDelimiter),
+			ParseFunction:   parsers.List(&dlc.linkStrs, &dlc.pairDelimiter),
+			UnparseFunction: unparsers.List(&dlc.linkStrs, &dlc.pairDelimiter),
 		},
 		{
 			Key:             "LOGS_SKIP_JOIN_LEAVE",
@@ -221,6 +238,45 @@ func (dlc *discordConfig) Options() configo.Options {
 			ParseFunction:   parsers.Bool(&dlc.skipWhisperMessages),
 			UnparseFunction: unparsers.Bool(&dlc.skipWhisperMessages),
 		},
+		{
+			Key:             "MODERATOR_ROLES",
+			Description:     "PAIR_DELIMITER separated list of discord role IDs that are allowed to execute commands, everyone is allowed if empty.",
+			ParseFunction:   parsers.ListToSet(&dlc.moderatorRoles, &dlc.pairDelimiter),
+			UnparseFunction: unparsers.SetToList(&dlc.moderatorRoles, &dlc.pairDelimiter),
+		},
+		{
+			Key:             "BLOCKED_COMMANDS",
+			Description:     "PAIR_DELIMITER separated list of econ command patterns that must not be executed via discord, e.g. shutdown,sv_rcon_*",
+			DefaultValue:    "sv_rcon_password,sv_rcon_mod_password,ec_password",
+			ParseFunction:   parsers.List(&dlc.blockedCommands, &dlc.pairDelimiter),
+			UnparseFunction: unparsers.List(&dlc.blockedCommands, &dlc.pairDelimiter),
+		},
+		{
+			Key:             "ADMIN_CHANNELS",
+			Description:     "PAIR_DELIMITER separated list of discord channel IDs that are allowed to see sensitive player data like IPs.",
+			ParseFunction:   parsers.ListToSet(&dlc.adminChannels, &dlc.pairDelimiter),
+			UnparseFunction: unparsers.SetToList(&dlc.adminChannels, &dlc.pairDelimiter),
+		},
+		{
+			Key:             "AUDIT_CHANNEL",
+			Description:     "Optional discord channel ID that receives an audit trail of all commands that are executed via discord.",
+			ParseFunction:   parsers.String(&dlc.auditChannelStr),
+			UnparseFunction: unparsers.String(&dlc.auditChannelStr),
+		},
+		{
+			Key:             "CONFIRM_COMMANDS",
+			Description:     "PAIR_DELIMITER separated list of destructive econ command patterns that need to be confirmed with a reaction before being executed, e.g. shutdown,restart,sv_map",
+			DefaultValue:    "shutdown,restart",
+			ParseFunction:   parsers.List(&dlc.confirmCommands, &dlc.pairDelimiter),
+			UnparseFunction: unparsers.List(&dlc.confirmCommands, &dlc.pairDelimiter),
+		},
+		{
+			Key:             "CONFIRM_TIMEOUT",
+			Description:     "The time a destructive command may be confirmed by its author or another moderator (e.g. 30s, 1m)",
+			DefaultValue:    "30s",
+			ParseFunction:   parsers.Duration(&dlc.confirmTimeout),
+			UnparseFunction: unparsers.Duration(&dlc.confirmTimeout),
+		},
 	}
 	return options
 }
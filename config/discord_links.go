@@ -0,0 +1,394 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+const (
+	// CategoryChat contains all chat, team chat and whisper events
+	CategoryChat = "chat"
+	// CategoryVote contains kick, spec and option votes
+	CategoryVote = "vote"
+	// CategoryJoin contains joining and leaving players
+	CategoryJoin = "join"
+	// CategoryMap contains map changes
+	CategoryMap = "map"
+
+	categoryDelimiter = "+"
+
+	linksStateName = "links"
+)
+
+var (
+	eventCategories = map[string]string{
+		events.TypeChat:              CategoryChat,
+		events.TypeChatTeam:          CategoryChat,
+		events.TypeChatWhisper:       CategoryChat,
+		events.TypeVoteKickStarted:   CategoryVote,
+		events.TypeVoteSpecStarted:   CategoryVote,
+		events.TypeVoteOptionStarted: CategoryVote,
+		events.TypePlayerJoined:      CategoryJoin,
+		events.TypePlayerLeft:        CategoryJoin,
+		events.TypeMapChanged:        CategoryMap,
+	}
+	categories = []string{CategoryChat, CategoryVote, CategoryJoin, CategoryMap}
+)
+
+// link connects an econ address with a discord channel.
+// The events of the econ address are routed to the channel if their category is
+// part of the link's categories. An empty set of categories routes all events.
+type link struct {
+	// addr is the normalized econ address that is used in order to look up the link
+	addr string
+	// queue is the econ address as it was configured, the server's queue is named after it
+	queue      string
+	channelID  discord.ChannelID
+	categories map[string]bool
+}
+
+func (l *link) routes(eventType string) bool {
+	if len(l.categories) == 0 {
+		return true
+	}
+	return l.categories[eventCategories[eventType]]
+}
+
+func (l *link) String(keyValueDelimiter string) string {
+	parts := []string{l.queue, l.channelID.String()}
+	if len(l.categories) > 0 {
+		parts = append(parts, strings.Join(l.Categories(), categoryDelimiter))
+	}
+	return strings.Join(parts, keyValueDelimiter)
+}
+
+// Categories returns the sorted categories of the link.
+func (l *link) Categories() []string {
+	result := make([]string, 0, len(l.categories))
+	for category := range l.categories {
+		result = append(result, category)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Link is the serializable representation of a link.
+type Link struct {
+	// Address is the econ address as it was configured
+	Address   string            `json:"address"`
+	ChannelID discord.ChannelID `json:"channel_id"`
+	// Categories is empty in case all event categories are routed.
+	Categories []string `json:"categories,omitempty"`
+}
+
+func (l *link) Link() Link {
+	return Link{
+		Address:    l.queue,
+		ChannelID:  l.channelID,
+		Categories: l.Categories(),
+	}
+}
+
+func fromLink(l Link) (link, error) {
+	categorySet, err := parseCategories(l.Categories...)
+	if err != nil {
+		return link{}, err
+	}
+	return newLink(l.Address, l.ChannelID, categorySet)
+}
+
+// newLink normalizes the econ address and keeps it as it was configured as the queue name.
+func newLink(econAddr string, channelID discord.ChannelID, categorySet map[string]bool) (link, error) {
+	addr, err := NormalizeAddress(econAddr)
+	if err != nil {
+		return link{}, err
+	}
+	return link{
+		addr:       addr,
+		queue:      strings.TrimSpace(econAddr),
+		channelID:  channelID,
+		categories: categorySet,
+	}, nil
+}
+
+func parseCategories(list ...string) (map[string]bool, error) {
+	result := make(map[string]bool, len(list))
+	for _, category := range list {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if category == "" {
+			continue
+		}
+		if !contains(categories, category) {
+			return nil, fmt.Errorf("unknown event category %s, expected one of: %s", category, strings.Join(categories, ", "))
+		}
+		result[category] = true
+	}
+	return result, nil
+}
+
+// parseLink parses address->channel[->category+category]
+func parseLink(linkStr, keyValueDelimiter string) (link, error) {
+	parts := strings.Split(linkStr, keyValueDelimiter)
+	if len(parts) < 2 || len(parts) > 3 {
+		return link{}, fmt.Errorf("invalid link: %s", linkStr)
+	}
+	value, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return link{}, fmt.Errorf("invalid channel ID: %s for %s", parts[1], parts[0])
+	}
+
+	categorySet := make(map[string]bool)
+	if len(parts) == 3 {
+		categorySet, err = parseCategories(strings.Split(parts[2], categoryDelimiter)...)
+		if err != nil {
+			return link{}, err
+		}
+	}
+	return newLink(parts[0], discord.ChannelID(value), categorySet)
+}
+
+// Links returns all links sorted by econ address.
+func (dlc *discordConfig) Links() []Link {
+	dlc.RLock()
+	defer dlc.RUnlock()
+	return dlc.linkList()
+}
+
+// expects the lock to be held
+func (dlc *discordConfig) linkList() []Link {
+	return toLinkList(dlc.links)
+}
+
+func toLinkList(list []link) []Link {
+	links := make([]Link, 0, len(list))
+	for _, l := range list {
+		links = append(links, l.Link())
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Address != links[j].Address {
+			return links[i].Address < links[j].Address
+		}
+		return links[i].ChannelID < links[j].ChannelID
+	})
+	return links
+}
+
+// loadLinks replaces the links from the environment with the links of the state file, if it exists.
+// expects the lock to be held
+func (dlc *discordConfig) loadLinks() error {
+	links := make([]Link, 0)
+	found, err := loadState(linksStateName, &links)
+	if err != nil || !found {
+		return err
+	}
+
+	dlc.links = make([]link, 0, len(links))
+	for _, l := range links {
+		converted, err := fromLink(l)
+		if err != nil {
+			return fmt.Errorf("invalid link in state file: %w", err)
+		}
+		dlc.links = append(dlc.links, converted)
+	}
+	dlc.updateLinkStrs()
+	return nil
+}
+
+// setLinks persists the passed links immediately and replaces the current links only if they
+// were persisted successfully, expects the lock to be held
+func (dlc *discordConfig) setLinks(links []link) error {
+	err := saveState(linksStateName, toLinkList(links))
+	if err != nil {
+		return err
+	}
+	dlc.links = links
+	dlc.updateLinkStrs()
+	return nil
+}
+
+// expects the lock to be held
+func (dlc *discordConfig) indexOfLink(econAddr string, channelID discord.ChannelID) int {
+	for idx, l := range dlc.links {
+		if l.addr == econAddr && l.channelID == channelID {
+			return idx
+		}
+	}
+	return -1
+}
+
+// updateLinkStrs must be called after every link modification in order to keep
+// the serializable link strings up to date, expects the lock to be held.
+func (dlc *discordConfig) updateLinkStrs() {
+	dlc.linkStrs = make([]string, 0, len(dlc.links))
+	for _, l := range dlc.links {
+		dlc.linkStrs = append(dlc.linkStrs, l.String(dlc.keyValueDelimiter))
+	}
+	sort.Strings(dlc.linkStrs)
+}
+
+// GetChannels returns all channels that the events of the given type of the econ address are routed to.
+func (dlc *discordConfig) GetChannels(econAddr, eventType string) ([]discord.ChannelID, error) {
+	econAddr, err := NormalizeAddress(econAddr)
+	if err != nil {
+		return nil, err
+	}
+	dlc.RLock()
+	defer dlc.RUnlock()
+
+	found := false
+	channelIDs := make([]discord.ChannelID, 0, 1)
+	for _, l := range dlc.links {
+		if l.addr != econAddr {
+			continue
+		}
+		found = true
+		if l.routes(eventType) {
+			channelIDs = append(channelIDs, l.channelID)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("unknown econ address: %s", econAddr)
+	}
+	return channelIDs, nil
+}
+
+// GetEconAddr returns the econ address of a channel that is linked to exactly one econ address.
+func (dlc *discordConfig) GetEconAddr(channelID discord.ChannelID) (string, error) {
+	addrs := dlc.GetChannelEconAddrs(channelID)
+	switch len(addrs) {
+	case 0:
+		return "", fmt.Errorf("unknown channel id: %d", channelID)
+	case 1:
+		return addrs[0], nil
+	default:
+		return "", fmt.Errorf("channel %d is linked to multiple servers, please specify a target", channelID)
+	}
+}
+
+// GetChannelEconAddrs returns the sorted econ addresses that are linked to the channel.
+func (dlc *discordConfig) GetChannelEconAddrs(channelID discord.ChannelID) []string {
+	dlc.RLock()
+	defer dlc.RUnlock()
+
+	addrs := make([]string, 0, 1)
+	for _, l := range dlc.links {
+		if l.channelID == channelID && !contains(addrs, l.addr) {
+			addrs = append(addrs, l.addr)
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// queueName returns the econ address of the first link of the server as it was configured.
+func (dlc *discordConfig) queueName(econAddr string) (string, bool) {
+	econAddr, err := NormalizeAddress(econAddr)
+	if err != nil {
+		return "", false
+	}
+	dlc.RLock()
+	defer dlc.RUnlock()
+	for _, l := range dlc.links {
+		if l.addr == econAddr {
+			return l.queue, true
+		}
+	}
+	return "", false
+}
+
+// IsSharedChannel returns true if the channel is linked to more than one econ address.
+func (dlc *discordConfig) IsSharedChannel(channelID discord.ChannelID) bool {
+	return len(dlc.GetChannelEconAddrs(channelID)) > 1
+}
+
+// GetEconAddrs returns a sorted list of all linked econ addresses.
+func (dlc *discordConfig) GetEconAddrs() []string {
+	dlc.RLock()
+	defer dlc.RUnlock()
+	addrs := make([]string, 0, len(dlc.links))
+	for _, l := range dlc.links {
+		if !contains(addrs, l.addr) {
+			addrs = append(addrs, l.addr)
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// AddLink links the econ address and the channel for the passed event categories.
+// All event categories are routed to the channel if no categories are passed.
+// An existing link between the econ address and the channel is updated.
+func (dlc *discordConfig) AddLink(econAddr string, channelID discord.ChannelID, categories ...string) error {
+	categorySet, err := parseCategories(categories...)
+	if err != nil {
+		return err
+	}
+	l, err := newLink(econAddr, channelID, categorySet)
+	if err != nil {
+		return err
+	}
+
+	dlc.Lock()
+	defer dlc.Unlock()
+
+	links := make([]link, len(dlc.links), len(dlc.links)+1)
+	copy(links, dlc.links)
+	idx := dlc.indexOfLink(l.addr, channelID)
+	if idx >= 0 {
+		links[idx] = l
+	} else {
+		links = append(links, l)
+	}
+	return dlc.setLinks(links)
+}
+
+// RemoveAddressLink removes all links of the econ address
+func (dlc *discordConfig) RemoveAddressLink(econAddr string) ([]discord.ChannelID, error) {
+	econAddr, err := NormalizeAddress(econAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	dlc.Lock()
+	defer dlc.Unlock()
+
+	channelIDs := make([]discord.ChannelID, 0, 1)
+	remaining := make([]link, 0, len(dlc.links))
+	for _, l := range dlc.links {
+		if l.addr == econAddr {
+			channelIDs = append(channelIDs, l.channelID)
+			continue
+		}
+		remaining = append(remaining, l)
+	}
+	if len(channelIDs) == 0 {
+		return nil, fmt.Errorf("address not found %s", econAddr)
+	}
+	return channelIDs, dlc.setLinks(remaining)
+}
+
+// RemoveChannelLink removes all links of the channel
+func (dlc *discordConfig) RemoveChannelLink(channelID discord.ChannelID) ([]string, error) {
+
+	dlc.Lock()
+	defer dlc.Unlock()
+
+	addrs := make([]string, 0, 1)
+	remaining := make([]link, 0, len(dlc.links))
+	for _, l := range dlc.links {
+		if l.channelID == channelID {
+			addrs = append(addrs, l.addr)
+			continue
+		}
+		remaining = append(remaining, l)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("channel not found %d", channelID)
+	}
+	return addrs, dlc.setLinks(remaining)
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+func TestParseLink(t *testing.T) {
+	tests := []struct {
+		linkStr    string
+		addr       string
+		queue      string
+		channelID  discord.ChannelID
+		categories []string
+		wantErr    bool
+	}{
+		{"127.0.0.1:8303->123", "127.0.0.1:8303", "127.0.0.1:8303", 123, []string{}, false},
+		{"[0:0::1]:8303->123", "[::1]:8303", "[0:0::1]:8303", 123, []string{}, false},
+		{"Econ.Example.com:8303->123->chat", "econ.example.com:8303", "Econ.Example.com:8303", 123, []string{CategoryChat}, false},
+		{"127.0.0.1:8303->123->vote+chat", "127.0.0.1:8303", "127.0.0.1:8303", 123, []string{CategoryChat, CategoryVote}, false},
+		{"127.0.0.1:8303->123-> Join + MAP ", "127.0.0.1:8303", "127.0.0.1:8303", 123, []string{CategoryJoin, CategoryMap}, false},
+		{"127.0.0.1:8303->123->chat+", "127.0.0.1:8303", "127.0.0.1:8303", 123, []string{CategoryChat}, false},
+		{"127.0.0.1:8303->123->", "127.0.0.1:8303", "127.0.0.1:8303", 123, []string{}, false},
+		{"127.0.0.1:8303->123->whisper", "", "", 0, nil, true},
+		{"127.0.0.1:8303->123->chat->vote", "", "", 0, nil, true},
+		{"127.0.0.1:8303->channel", "", "", 0, nil, true},
+		{"127.0.0.1:8303->-1", "", "", 0, nil, true},
+		{"127.0.0.1:8303", "", "", 0, nil, true},
+		{"::1:8303->123", "", "", 0, nil, true},
+		{"", "", "", 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.linkStr, func(t *testing.T) {
+			got, err := parseLink(tt.linkStr, "->")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLink(%q) error = %v, wantErr %v", tt.linkStr, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.addr != tt.addr || got.queue != tt.queue || got.channelID != tt.channelID {
+				t.Errorf("parseLink(%q) = %s %s %d, want %s %s %d", tt.linkStr, got.addr, got.queue, got.channelID, tt.addr, tt.queue, tt.channelID)
+			}
+			if categories := got.Categories(); !reflect.DeepEqual(categories, tt.categories) {
+				t.Errorf("parseLink(%q).Categories() = %v, want %v", tt.linkStr, categories, tt.categories)
+			}
+		})
+	}
+}
+
+func TestLinkString(t *testing.T) {
+	tests := []struct {
+		linkStr string
+		want    string
+	}{
+		{"127.0.0.1:8303->123", "127.0.0.1:8303->123"},
+		{"[0:0::1]:8303->123", "[0:0::1]:8303->123"},
+		{"127.0.0.1:8303->123->vote+chat", "127.0.0.1:8303->123->chat+vote"},
+		{"127.0.0.1:8303->123->", "127.0.0.1:8303->123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.linkStr, func(t *testing.T) {
+			l, err := parseLink(tt.linkStr, "->")
+			if err != nil {
+				t.Fatalf("parseLink(%q) unexpected error: %v", tt.linkStr, err)
+			}
+			if got := l.String("->"); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLinkRoutes(t *testing.T) {
+	tests := []struct {
+		linkStr   string
+		eventType string
+		want      bool
+	}{
+		{"127.0.0.1:8303->123", events.TypeChat, true},
+		{"127.0.0.1:8303->123", events.TypeMapChanged, true},
+		{"127.0.0.1:8303->123", events.TypeServerState, true},
+		{"127.0.0.1:8303->123->chat", events.TypeChat, true},
+		{"127.0.0.1:8303->123->chat", events.TypeChatTeam, true},
+		{"127.0.0.1:8303->123->chat", events.TypeChatWhisper, true},
+		{"127.0.0.1:8303->123->chat", events.TypeVoteKickStarted, false},
+		{"127.0.0.1:8303->123->vote", events.TypeVoteOptionStarted, true},
+		{"127.0.0.1:8303->123->join+map", events.TypePlayerLeft, true},
+		{"127.0.0.1:8303->123->join+map", events.TypeMapChanged, true},
+		// events without a category are only routed to links of all categories
+		{"127.0.0.1:8303->123->chat+vote+join+map", events.TypeServerState, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.linkStr+" "+tt.eventType, func(t *testing.T) {
+			l, err := parseLink(tt.linkStr, "->")
+			if err != nil {
+				t.Fatalf("parseLink(%q) unexpected error: %v", tt.linkStr, err)
+			}
+			if got := l.routes(tt.eventType); got != tt.want {
+				t.Errorf("routes(%s) = %v, want %v", tt.eventType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetChannels(t *testing.T) {
+	dlc := &discordConfig{
+		keyValueDelimiter: "->",
+		linkStrs: []string{
+			"127.0.0.1:8303->1",
+			"127.0.0.1:8303->2->vote",
+			"[0:0::1]:8303->2->chat+join",
+		},
+	}
+	useStatePath(t)
+	if err := dlc.PostParse(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		econAddr  string
+		eventType string
+		want      []discord.ChannelID
+		wantErr   bool
+	}{
+		{"127.0.0.1:8303", events.TypeChat, []discord.ChannelID{1}, false},
+		{"127.0.0.1:8303", events.TypeVoteKickStarted, []discord.ChannelID{1, 2}, false},
+		{"[::1]:8303", events.TypeChatTeam, []discord.ChannelID{2}, false},
+		{"[::1]:8303", events.TypeMapChanged, []discord.ChannelID{}, false},
+		{"127.0.0.1:8304", events.TypeChat, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.econAddr+" "+tt.eventType, func(t *testing.T) {
+			got, err := dlc.GetChannels(tt.econAddr, tt.eventType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetChannels() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetChannels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got, want := dlc.GetChannelEconAddrs(2), []string{"127.0.0.1:8303", "[::1]:8303"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetChannelEconAddrs(2) = %v, want %v", got, want)
+	}
+	if _, err := dlc.GetEconAddr(2); err == nil {
+		t.Error("GetEconAddr(2) expected an error for a shared channel")
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/go-redis/redis"
+	configo "github.com/jxsl13/simple-configo"
+	"github.com/jxsl13/simple-configo/parsers"
+	"github.com/jxsl13/simple-configo/unparsers"
+)
+
+type historyConfig struct {
+	redisDatabase int
+	rdb           *redis.Client
+}
+
+// RDB returns the redis client that is used to store the player history.
+func (hc *historyConfig) RDB() *redis.Client {
+	return hc.rdb
+}
+
+func (hc *historyConfig) PostParse() error {
+	if detectVPNCfg != nil && hc.redisDatabase == detectVPNCfg.redisDatabase {
+		return fmt.Errorf("HISTORY_REDIS_DB must differ from REDIS_DB, both are %d", hc.redisDatabase)
+	}
+	hc.rdb = redis.NewClient(redisCfg.redisOptions(hc.redisDatabase))
+	return hc.rdb.Ping().Err()
+}
+
+func (hc *historyConfig) Close() error {
+	return hc.rdb.Close()
+}
+
+func (hc *historyConfig) Name() string {
+	return "player-history"
+}
+
+func (hc *historyConfig) Options() configo.Options {
+	return configo.Options{
+		{
+			Key:             "HISTORY_REDIS_DB",
+			Description:     "Is one of the 16 [0:15] ditinct databases that redis offers, must differ from REDIS_DB.",
+			DefaultValue:    "2",
+			ParseFunction:   parsers.RangesInt(&hc.redisDatabase, 0, 15),
+			UnparseFunction: unparsers.Int(&hc.redisDatabase),
+		},
+	}
+}
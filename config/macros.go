@@ -0,0 +1,247 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/console"
+	configo "github.com/jxsl13/simple-configo"
+	"github.com/jxsl13/simple-configo/parsers"
+	"github.com/jxsl13/simple-configo/unparsers"
+)
+
+var (
+	macrosStateName = "macros"
+	// {1}, {2}, ... are replaced with the arguments of the macro
+	macroParamRegex = regexp.MustCompile(`\{([1-9][0-9]*)\}`)
+	// sleep <duration> delays the following steps
+	macroSleepPrefix = "sleep "
+)
+
+// MacroStep is either an econ command or a delay before the next step.
+type MacroStep struct {
+	Command string
+	Delay   time.Duration
+}
+
+// Macro is a named sequence of econ commands with optional delays between them.
+type Macro struct {
+	Name    string   `json:"name"`
+	Lines   []string `json:"lines"`
+	Creator string   `json:"creator"`
+
+	steps []MacroStep
+	// params is the number of arguments the macro expects
+	params int
+}
+
+// Params is the number of arguments the macro expects.
+func (m Macro) Params() int {
+	return m.params
+}
+
+func newMacroConfig() *macroConfig {
+	return &macroConfig{
+		macros: make(map[string]Macro),
+	}
+}
+
+type macroConfig struct {
+	maxDelay time.Duration
+	macros   map[string]Macro
+
+	sync.RWMutex
+}
+
+func (mc *macroConfig) PostParse() error {
+	mc.Lock()
+	defer mc.Unlock()
+
+	state := make([]Macro, 0)
+	found, err := loadState(macrosStateName, &state)
+	if err != nil || !found {
+		return err
+	}
+	for _, macro := range state {
+		macro, err = mc.parseMacro(macro.Name, macro.Lines, macro.Creator)
+		if err != nil {
+			return fmt.Errorf("invalid macro state: %w", err)
+		}
+		mc.macros[macro.Name] = macro
+	}
+	return nil
+}
+
+func (mc *macroConfig) Close() error {
+	return nil
+}
+
+// setMacros persists the passed macros immediately and replaces the current ones only if
+// they were persisted successfully, expects the lock to be held
+func (mc *macroConfig) setMacros(macros map[string]Macro) error {
+	err := saveState(macrosStateName, sortedMacros(macros))
+	if err != nil {
+		return err
+	}
+	mc.macros = macros
+	return nil
+}
+
+func (mc *macroConfig) copyMacros() map[string]Macro {
+	result := make(map[string]Macro, len(mc.macros))
+	for name, macro := range mc.macros {
+		result[name] = macro
+	}
+	return result
+}
+
+// parseMacro parses the lines of a macro, every line is either an econ command
+// or a sleep <duration> step. Expects the lock to be held.
+func (mc *macroConfig) parseMacro(name string, lines []string, creator string) (Macro, error) {
+	if err := validateName(name); err != nil {
+		return Macro{}, fmt.Errorf("invalid macro name %s: %w", name, err)
+	}
+
+	macro := Macro{
+		Name:    name,
+		Lines:   make([]string, 0, len(lines)),
+		Creator: creator,
+		steps:   make([]MacroStep, 0, len(lines)),
+	}
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		macro.Lines = append(macro.Lines, line)
+
+		if strings.HasPrefix(strings.ToLower(line), macroSleepPrefix) {
+			delay, err := time.ParseDuration(strings.TrimSpace(line[len(macroSleepPrefix):]))
+			if err != nil {
+				return Macro{}, fmt.Errorf("invalid delay of macro %s: %w", name, err)
+			}
+			if delay <= 0 || delay > mc.maxDelay {
+				return Macro{}, fmt.Errorf("the delays of macro %s must be within (0s:%s]", name, mc.maxDelay)
+			}
+			macro.steps = append(macro.steps, MacroStep{Delay: delay})
+			continue
+		}
+
+		for _, match := range macroParamRegex.FindAllStringSubmatch(line, -1) {
+			if idx, _ := strconv.Atoi(match[1]); idx > macro.params {
+				macro.params = idx
+			}
+		}
+		macro.steps = append(macro.steps, MacroStep{Command: line})
+	}
+
+	if len(macro.steps) == 0 {
+		return Macro{}, fmt.Errorf("macro %s does not contain any commands", name)
+	}
+	return macro, nil
+}
+
+// DefineMacro creates or replaces a macro, every line is either an econ command
+// with optional parameters {1}, {2}, ... or a sleep <duration> step.
+func (mc *macroConfig) DefineMacro(name string, lines []string, creator string) (Macro, error) {
+	mc.Lock()
+	defer mc.Unlock()
+
+	macro, err := mc.parseMacro(name, lines, creator)
+	if err != nil {
+		return Macro{}, err
+	}
+	macros := mc.copyMacros()
+	macros[name] = macro
+	err = mc.setMacros(macros)
+	if err != nil {
+		return Macro{}, err
+	}
+	return macro, nil
+}
+
+// RemoveMacro removes the named macro.
+func (mc *macroConfig) RemoveMacro(name string) error {
+	mc.Lock()
+	defer mc.Unlock()
+	if _, found := mc.macros[name]; !found {
+		return fmt.Errorf("macro not found: %s", name)
+	}
+	macros := mc.copyMacros()
+	delete(macros, name)
+	return mc.setMacros(macros)
+}
+
+// Macros returns all macros sorted by their name.
+func (mc *macroConfig) Macros() []Macro {
+	mc.RLock()
+	defer mc.RUnlock()
+	return mc.list()
+}
+
+// list expects the lock to be held
+func (mc *macroConfig) list() []Macro {
+	return sortedMacros(mc.macros)
+}
+
+func sortedMacros(macros map[string]Macro) []Macro {
+	result := make([]Macro, 0, len(macros))
+	for _, macro := range macros {
+		result = append(result, macro)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Expand replaces the parameters of the named macro with the passed arguments,
+// which are escaped within quoted arguments and quoted otherwise.
+func (mc *macroConfig) Expand(name string, args []string) ([]MacroStep, error) {
+	mc.RLock()
+	macro, found := mc.macros[name]
+	mc.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("macro not found: %s", name)
+	}
+	if len(args) < macro.params {
+		return nil, fmt.Errorf("macro %s expects %d arguments", name, macro.params)
+	}
+
+	steps := make([]MacroStep, 0, len(macro.steps))
+	for _, step := range macro.steps {
+		if step.Command != "" {
+			// every argument is a single console argument
+			step.Command = console.ReplaceAllFunc(step.Command, macroParamRegex, func(param string, inQuotes bool) string {
+				idx, _ := strconv.Atoi(param[1 : len(param)-1])
+				if inQuotes {
+					return console.Escape(args[idx-1])
+				}
+				return console.Quote(args[idx-1])
+			})
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func (mc *macroConfig) Name() string {
+	return "macros"
+}
+
+func (mc *macroConfig) Options() configo.Options {
+	return configo.Options{
+		{
+			Key:             "MACRO_MAX_DELAY",
+			Description:     "The maximum duration of a single sleep step of a macro (e.g. 30s, 5m)",
+			DefaultValue:    "5m",
+			ParseFunction:   parsers.Duration(&mc.maxDelay),
+			UnparseFunction: unparsers.Duration(&mc.maxDelay),
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpand(t *testing.T) {
+	useStatePath(t)
+	mc := newMacroConfig()
+	mc.maxDelay = time.Minute
+	_, err := mc.DefineMacro("event", []string{
+		`sv_map {1}`,
+		"sleep 10s",
+		`say "next map: {1}, {2}"`,
+	}, "tester")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []MacroStep
+		wantErr bool
+	}{
+		{"arguments", []string{"ctf5", "have fun"}, []MacroStep{
+			{Command: `sv_map "ctf5"`},
+			{Delay: 10 * time.Second},
+			{Command: `say "next map: ctf5, have fun"`},
+		}, false},
+		{"injection", []string{`ctf5"; shutdown`, `x\`}, []MacroStep{
+			{Command: `sv_map "ctf5\"; shutdown"`},
+			{Delay: 10 * time.Second},
+			{Command: `say "next map: ctf5\"; shutdown, x"`},
+		}, false},
+		{"parameter within argument", []string{"{2}", "ctf5"}, []MacroStep{
+			{Command: `sv_map "{2}"`},
+			{Delay: 10 * time.Second},
+			{Command: `say "next map: {2}, ctf5"`},
+		}, false},
+		{"missing argument", []string{"ctf5"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mc.Expand("event", tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+	"github.com/diamondburned/arikawa/v2/discord"
+	configo "github.com/jxsl13/simple-configo"
+	"github.com/jxsl13/simple-configo/parsers"
+	"github.com/jxsl13/simple-configo/unparsers"
+)
+
+const (
+	ActionKick = "kick"
+	ActionBan  = "ban"
+	ActionMute = "mute"
+	ActionSpec = "spec"
+)
+
+type moderationConfig struct {
+	kickCommand  *commandTemplate
+	banCommand   *commandTemplate
+	muteCommand  *commandTemplate
+	specCommand  *commandTemplate
+	banDuration  time.Duration
+	muteDuration time.Duration
+	reason       string
+
+	// reactions maps unicode emojis or custom emojis (name:id) to moderation actions
+	reactions map[string]string
+
+	sync.RWMutex
+}
+
+func (mc *moderationConfig) PostParse() error {
+	for emoji, action := range mc.reactions {
+		switch action {
+		case ActionKick, ActionBan, ActionMute, ActionSpec:
+		default:
+			return fmt.Errorf("invalid moderation action of reaction %s: %s", emoji, action)
+		}
+	}
+	return nil
+}
+
+func (mc *moderationConfig) Close() error {
+	return nil
+}
+
+// Command fills the command template of the moderation action with the data of the player
+// on the server. A zero duration or an empty reason are replaced with the configured default values.
+// The templates quote or escape the reason, so it may contain any characters.
+func (mc *moderationConfig) Command(action string, player dto.Player, server string, duration time.Duration, reason string) (string, error) {
+	mc.RLock()
+	defer mc.RUnlock()
+
+	var template *commandTemplate
+	switch action {
+	case ActionKick:
+		template = mc.kickCommand
+	case ActionBan:
+		template = mc.banCommand
+		if duration == 0 {
+			duration = mc.banDuration
+		}
+	case ActionMute:
+		template = mc.muteCommand
+		if duration == 0 {
+			duration = mc.muteDuration
+		}
+	case ActionSpec:
+		template = mc.specCommand
+	default:
+		return "", fmt.Errorf("unknown moderation action: %s", action)
+	}
+
+	if reason == "" {
+		reason = mc.reason
+	}
+	if template.Uses("IP") && player.IP == "" {
+		return "", fmt.Errorf("the IP of the player %s is unknown", player.Name)
+	}
+
+	return template.Execute(newCommandData(player, server, duration, reason))
+}
+
+// ReactionAction returns the moderation action that is executed when a moderator
+// adds the emoji as reaction to a logged message.
+func (mc *moderationConfig) ReactionAction(emoji discord.Emoji) (string, bool) {
+	mc.RLock()
+	defer mc.RUnlock()
+	action, found := mc.reactions[string(emoji.APIString())]
+	return action, found
+}
+
+func (mc *moderationConfig) Name() string {
+	return "moderation"
+}
+
+func (mc *moderationConfig) Options() configo.Options {
+	return configo.Options{
+		{
+			Key:             "MOD_KICK_COMMAND",
+			Description:     "The command that is executed by !kick, you may use the same template variables and functions as in the BAN_COMMAND",
+			DefaultValue:    "kick {ID} {REASON}",
+			ParseFunction:   commandTemplateParser(&mc.kickCommand, "MOD_KICK_COMMAND"),
+			UnparseFunction: commandTemplateUnparser(&mc.kickCommand),
+		},
+		{
+			Key:             "MOD_BAN_COMMAND",
+			Description:     "The command that is executed by !ban, you may use the same template variables and functions as in the BAN_COMMAND",
+			DefaultValue:    "ban {IP} {DURATION:MINUTES} {REASON}",
+			ParseFunction:   commandTemplateParser(&mc.banCommand, "MOD_BAN_COMMAND"),
+			UnparseFunction: commandTemplateUnparser(&mc.banCommand),
+		},
+		{
+			Key:             "MOD_MUTE_COMMAND",
+			Description:     "The command that is executed by !mute, you may use the same template variables and functions as in the BAN_COMMAND",
+			DefaultValue:    "muteid {ID} {DURATION:SECONDS} {REASON}",
+			ParseFunction:   commandTemplateParser(&mc.muteCommand, "MOD_MUTE_COMMAND"),
+			UnparseFunction: commandTemplateUnparser(&mc.muteCommand),
+		},
+		{
+			Key:             "MOD_SPEC_COMMAND",
+			Description:     "The command that is executed by !spec, you may use the same template variables and functions as in the BAN_COMMAND",
+			DefaultValue:    "set_team {ID} -1",
+			ParseFunction:   commandTemplateParser(&mc.specCommand, "MOD_SPEC_COMMAND"),
+			UnparseFunction: commandTemplateUnparser(&mc.specCommand),
+		},
+		{
+			Key:             "MOD_BAN_DURATION",
+			Description:     "The default duration of a !ban (e.g. 10s, 5m, 1h, 1h5m10s, 24h)",
+			DefaultValue:    "24h",
+			ParseFunction:   parsers.Duration(&mc.banDuration),
+			UnparseFunction: unparsers.Duration(&mc.banDuration),
+		},
+		{
+			Key:             "MOD_MUTE_DURATION",
+			Description:     "The default duration of a !mute (e.g. 10s, 5m, 1h, 1h5m10s, 24h)",
+			DefaultValue:    "10m",
+			ParseFunction:   parsers.Duration(&mc.muteDuration),
+			UnparseFunction: unparsers.Duration(&mc.muteDuration),
+		},
+		{
+			Key:             "MOD_REASON",
+			Description:     "The default reason of moderation commands that are executed without a reason.",
+			DefaultValue:    "moderator decision",
+			ParseFunction:   parsers.String(&mc.reason),
+			UnparseFunction: unparsers.String(&mc.reason),
+		},
+		{
+			Key:             "MOD_REACTIONS",
+			Description:     "Reactions on logged messages that moderate the message's player: emoji->action,emoji2->action2 with the actions kick, ban, mute and spec, custom emojis are referenced via name:id",
+			DefaultValue:    "🔇->mute,👢->kick,🔨->ban",
+			ParseFunction:   optionalMap(&mc.reactions, &serverPairDelimiter, &serverKeyValueDelimiter),
+			UnparseFunction: unparsers.Map(&mc.reactions, &serverPairDelimiter, &serverKeyValueDelimiter),
+		},
+	}
+}
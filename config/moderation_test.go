@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/console"
+)
+
+// newTestModerationConfig parses the default values of the options.
+func newTestModerationConfig(t *testing.T) *moderationConfig {
+	t.Helper()
+	mc := &moderationConfig{}
+	for _, option := range mc.Options() {
+		if err := option.ParseFunction(option.DefaultValue); err != nil {
+			t.Fatalf("invalid default value of %s: %v", option.Key, err)
+		}
+	}
+	return mc
+}
+
+func TestModerationCommand(t *testing.T) {
+	mc := newTestModerationConfig(t)
+	player := dto.Player{ID: 3, Name: "tee", IP: "1.2.3.4"}
+
+	tests := []struct {
+		name     string
+		action   string
+		player   dto.Player
+		duration time.Duration
+		reason   string
+		want     string
+		wantErr  bool
+	}{
+		{"kick", ActionKick, player, 0, "spam", `kick 3 "spam"`, false},
+		{"default reason", ActionKick, player, 0, "", `kick 3 "moderator decision"`, false},
+		{"ban", ActionBan, player, time.Hour, "cheating", `ban 1.2.3.4 60 "cheating"`, false},
+		{"default ban duration", ActionBan, player, 0, "cheating", `ban 1.2.3.4 1440 "cheating"`, false},
+		{"unknown IP", ActionBan, dto.Player{ID: 3}, 0, "cheating", "", true},
+		{"mute", ActionMute, player, 0, "spam", `muteid 3 600 "spam"`, false},
+		{"spec", ActionSpec, player, 0, "afk", `set_team 3 -1`, false},
+		{"injection", ActionKick, player, 0, `spam"; shutdown`, `kick 3 "spam\"; shutdown"`, false},
+		{"trailing backslash", ActionKick, player, 0, `spam\`, `kick 3 "spam"`, false},
+		{"unknown action", "slap", player, 0, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mc.Command(tt.action, tt.player, "", tt.duration, tt.reason)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Command() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Command() = %q, want %q", got, tt.want)
+			}
+			if err == nil && len(console.Statements(got)) != 1 {
+				t.Errorf("Command() = %q, contains several statements", got)
+			}
+		})
+	}
+}
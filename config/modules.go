@@ -11,6 +11,12 @@ import (
 type moduleConfig struct {
 	enabledDiscordLog   bool
 	enabledVPNDetection bool
+	// requires redis
+	enabledPlayerHistory bool
+	enabledScheduler     bool
+	enabledVoteGuard     bool
+
+	statePath string
 }
 
 func (m *moduleConfig) PostParse() error {
@@ -21,6 +27,11 @@ func (m *moduleConfig) Close() error {
 	return nil
 }
 
+// StatePath is the folder that contains the state files that are modified at runtime.
+func (m *moduleConfig) StatePath() string {
+	return m.statePath
+}
+
 func (m *moduleConfig) Name() string {
 	return "modules"
 }
@@ -41,6 +52,34 @@ func (m *moduleConfig) Options() configo.Options {
 			ParseFunction:   parsers.Bool(&m.enabledVPNDetection),
 			UnparseFunction: unparsers.Bool(&m.enabledVPNDetection),
 		},
+		{
+			Key:             "ENABLE_PLAYER_HISTORY",
+			Description:     "Whether to store the history of joining players in redis, which enables the !whois command",
+			DefaultValue:    "false",
+			ParseFunction:   parsers.Bool(&m.enabledPlayerHistory),
+			UnparseFunction: unparsers.Bool(&m.enabledPlayerHistory),
+		},
+		{
+			Key:             "ENABLE_SCHEDULER",
+			Description:     "Whether to periodically execute econ commands that are scheduled via the !schedule command",
+			DefaultValue:    "false",
+			ParseFunction:   parsers.Bool(&m.enabledScheduler),
+			UnparseFunction: unparsers.Bool(&m.enabledScheduler),
+		},
+		{
+			Key:             "ENABLE_VOTE_GUARD",
+			Description:     "Whether to abort kick- and specvotes against protected players, votes of new or spamming players and option votes that violate the VOTE_OPTION_RULES",
+			DefaultValue:    "false",
+			ParseFunction:   parsers.Bool(&m.enabledVoteGuard),
+			UnparseFunction: unparsers.Bool(&m.enabledVoteGuard),
+		},
+		{
+			Key:             "STATE_PATH",
+			Description:     "The folder that contains the state that is modified at runtime, e.g. links, aliases and groups. The state takes precedence over the configured values.",
+			DefaultValue:    "./data/state",
+			ParseFunction:   parsers.String(&m.statePath),
+			UnparseFunction: unparsers.String(&m.statePath),
+		},
 	}
 }
 
@@ -57,3 +96,24 @@ func (m *moduleConfig) ErrIfVPNDetectionDisabled() error {
 	}
 	return nil
 }
+
+func (m *moduleConfig) ErrIfPlayerHistoryDisabled() error {
+	if !m.enabledPlayerHistory {
+		return fmt.Errorf("the player history module is disabled")
+	}
+	return nil
+}
+
+func (m *moduleConfig) ErrIfSchedulerDisabled() error {
+	if !m.enabledScheduler {
+		return fmt.Errorf("the scheduler module is disabled")
+	}
+	return nil
+}
+
+func (m *moduleConfig) ErrIfVoteGuardDisabled() error {
+	if !m.enabledVoteGuard {
+		return fmt.Errorf("the vote guard module is disabled")
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	configo "github.com/jxsl13/simple-configo"
+	"github.com/jxsl13/simple-configo/parsers"
 )
 
 type Config interface {
@@ -54,3 +55,16 @@ func unparse(cs ...Config) error {
 	}
 	return nil
 }
+
+// optionalMap behaves like parsers.Map, but does also accept an empty value.
+// The map is replaced instead of merged with previously parsed values, e.g. the default value.
+func optionalMap(out *map[string]string, pairDelimiter, keyValueDelimiter *string) configo.ParserFunc {
+	parseMap := parsers.Map(out, pairDelimiter, keyValueDelimiter)
+	return func(value string) error {
+		*out = make(map[string]string)
+		if value == "" {
+			return nil
+		}
+		return parseMap(value)
+	}
+}
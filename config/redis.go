@@ -0,0 +1,55 @@
+package config
+
+import (
+	"github.com/go-redis/redis"
+	configo "github.com/jxsl13/simple-configo"
+	"github.com/jxsl13/simple-configo/parsers"
+	"github.com/jxsl13/simple-configo/unparsers"
+)
+
+// redisConfig contains the connection of the redis server that is shared by all modules
+// that store their data in redis, every module uses its own database.
+type redisConfig struct {
+	address  string
+	password string
+}
+
+// redisOptions returns the connection options of the passed database.
+func (rc *redisConfig) redisOptions(db int) *redis.Options {
+	return &redis.Options{
+		Addr:     rc.address,
+		Password: rc.password,
+		DB:       db,
+	}
+}
+
+func (rc *redisConfig) PostParse() error {
+	return nil
+}
+
+func (rc *redisConfig) Close() error {
+	return nil
+}
+
+func (rc *redisConfig) Name() string {
+	return "redis"
+}
+
+func (rc *redisConfig) Options() configo.Options {
+	return configo.Options{
+		{
+			Key:             "REDIS_ADDRESS",
+			Mandatory:       true,
+			Description:     "The REDIS_ADDRESS must have the following format: <hostname/ip>:<port>",
+			DefaultValue:    "localhost:6379",
+			ParseFunction:   parsers.String(&rc.address),
+			UnparseFunction: unparsers.String(&rc.address),
+		},
+		{
+			Key:             "REDIS_PASSWORD",
+			Description:     "Pasword used for the redis database, can be left empty.",
+			ParseFunction:   parsers.String(&rc.password),
+			UnparseFunction: unparsers.String(&rc.password),
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/console"
+	"github.com/diamondburned/arikawa/v2/discord"
+	configo "github.com/jxsl13/simple-configo"
+	"github.com/jxsl13/simple-configo/parsers"
+	"github.com/jxsl13/simple-configo/unparsers"
+)
+
+func newSayConfig() *sayConfig {
+	return &sayConfig{
+		lastSaid: make(map[discord.UserID]time.Time),
+	}
+}
+
+// sayConfig configures the relay of discord messages into the in-game chat
+type sayConfig struct {
+	command   string
+	maxLength int
+	cooldown  time.Duration
+
+	// the last time a user's message was relayed
+	lastSaid map[discord.UserID]time.Time
+
+	sync.Mutex
+}
+
+func (sc *sayConfig) PostParse() error {
+	if !strings.Contains(sc.command, "{MESSAGE}") {
+		return fmt.Errorf("SAY_COMMAND must contain the variable {MESSAGE}")
+	}
+	return nil
+}
+
+func (sc *sayConfig) Close() error {
+	return nil
+}
+
+// Command constructs the econ command that relays the text of the author into the in-game chat.
+// An error is returned if the author needs to wait before sending the next message.
+func (sc *sayConfig) Command(authorID discord.UserID, author, text string) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", fmt.Errorf("empty message")
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	now := time.Now()
+	if last, found := sc.lastSaid[authorID]; found && now.Sub(last) < sc.cooldown {
+		return "", fmt.Errorf("please wait %s before sending the next message", (sc.cooldown - now.Sub(last)).Round(time.Second))
+	}
+	sc.lastSaid[authorID] = now
+
+	message := console.Truncate(fmt.Sprintf("%s: %s", author, text), sc.maxLength)
+	return strings.ReplaceAll(sc.command, "{MESSAGE}", console.Quote(message)), nil
+}
+
+func (sc *sayConfig) Name() string {
+	return "say"
+}
+
+func (sc *sayConfig) Options() configo.Options {
+	return configo.Options{
+		{
+			Key:             "SAY_COMMAND",
+			Description:     "The econ command that relays messages of !say into the in-game chat, {MESSAGE} is replaced with the quoted and escaped message",
+			DefaultValue:    "say {MESSAGE}",
+			ParseFunction:   parsers.String(&sc.command),
+			UnparseFunction: unparsers.String(&sc.command),
+		},
+		{
+			Key:             "SAY_MAX_LENGTH",
+			Description:     "The maximum number of characters of a relayed message including the author's name, longer messages are truncated",
+			DefaultValue:    "128",
+			ParseFunction:   parsers.RangesInt(&sc.maxLength, 1, 1024),
+			UnparseFunction: unparsers.Int(&sc.maxLength),
+		},
+		{
+			Key:             "SAY_COOLDOWN",
+			Description:     "The time a discord user has to wait between two messages that are relayed via !say (e.g. 5s, 1m)",
+			DefaultValue:    "5s",
+			ParseFunction:   parsers.Duration(&sc.cooldown),
+			UnparseFunction: unparsers.Duration(&sc.cooldown),
+		},
+	}
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/cron"
+	configo "github.com/jxsl13/simple-configo"
+	"github.com/jxsl13/simple-configo/parsers"
+	"github.com/jxsl13/simple-configo/unparsers"
+)
+
+var (
+	schedulesStateName = "schedules"
+)
+
+// ScheduleTargetBroadcast is the target of schedules that are executed on all servers.
+const ScheduleTargetBroadcast = "broadcast"
+
+// Schedule is an econ command that is executed periodically on a target
+type Schedule struct {
+	ID int `json:"id"`
+	// Cron is a five field cron expression or one of the shortcuts like @hourly
+	Cron string `json:"cron"`
+	// Target is a server alias, group, econ address or ScheduleTargetBroadcast
+	Target  string    `json:"target"`
+	Command string    `json:"command"`
+	Creator string    `json:"creator"`
+	Created time.Time `json:"created"`
+
+	schedule cron.Schedule
+}
+
+// schedulerState is the persisted representation of all schedules
+type schedulerState struct {
+	NextID    int        `json:"next_id"`
+	Schedules []Schedule `json:"schedules"`
+}
+
+func newSchedulerConfig() *schedulerConfig {
+	return &schedulerConfig{
+		schedules: make([]Schedule, 0),
+		nextID:    1,
+	}
+}
+
+type schedulerConfig struct {
+	timezone string
+	location *time.Location
+
+	schedules []Schedule
+	nextID    int
+
+	sync.RWMutex
+}
+
+func (sc *schedulerConfig) PostParse() error {
+	sc.Lock()
+	defer sc.Unlock()
+
+	location, err := time.LoadLocation(sc.timezone)
+	if err != nil {
+		return fmt.Errorf("invalid SCHEDULER_TIMEZONE: %w", err)
+	}
+	sc.location = location
+
+	state := schedulerState{}
+	found, err := loadState(schedulesStateName, &state)
+	if err != nil || !found {
+		return err
+	}
+
+	sc.schedules = make([]Schedule, 0, len(state.Schedules))
+	for _, schedule := range state.Schedules {
+		schedule.schedule, err = cron.Parse(schedule.Cron)
+		if err != nil {
+			return fmt.Errorf("invalid schedule %d: %w", schedule.ID, err)
+		}
+		sc.schedules = append(sc.schedules, schedule)
+	}
+	sc.nextID = state.NextID
+	if sc.nextID < 1 {
+		sc.nextID = 1
+	}
+	return nil
+}
+
+func (sc *schedulerConfig) Close() error {
+	return nil
+}
+
+// setState persists the passed schedules immediately and replaces the current ones only if
+// they were persisted successfully, expects the lock to be held
+func (sc *schedulerConfig) setState(nextID int, schedules []Schedule) error {
+	err := saveState(schedulesStateName, schedulerState{
+		NextID:    nextID,
+		Schedules: schedules,
+	})
+	if err != nil {
+		return err
+	}
+	sc.nextID = nextID
+	sc.schedules = schedules
+	return nil
+}
+
+// Location is the time zone that the cron expressions are evaluated in.
+func (sc *schedulerConfig) Location() *time.Location {
+	sc.RLock()
+	defer sc.RUnlock()
+	return sc.location
+}
+
+// AddSchedule validates and persists a new schedule.
+func (sc *schedulerConfig) AddSchedule(cronExpr, target, command, creator string) (Schedule, error) {
+	parsed, err := cron.Parse(cronExpr)
+	if err != nil {
+		return Schedule{}, err
+	}
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return Schedule{}, fmt.Errorf("empty command")
+	}
+	if !strings.EqualFold(target, ScheduleTargetBroadcast) {
+		if _, err := Servers().Resolve(target); err != nil {
+			return Schedule{}, err
+		}
+	} else {
+		target = ScheduleTargetBroadcast
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	schedule := Schedule{
+		ID:       sc.nextID,
+		Cron:     cronExpr,
+		Target:   target,
+		Command:  command,
+		Creator:  creator,
+		Created:  time.Now(),
+		schedule: parsed,
+	}
+	schedules := make([]Schedule, len(sc.schedules), len(sc.schedules)+1)
+	copy(schedules, sc.schedules)
+	schedules = append(schedules, schedule)
+	err = sc.setState(sc.nextID+1, schedules)
+	if err != nil {
+		return Schedule{}, err
+	}
+	return schedule, nil
+}
+
+// RemoveSchedule removes the schedule with the passed ID.
+func (sc *schedulerConfig) RemoveSchedule(id int) (Schedule, error) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	for idx, schedule := range sc.schedules {
+		if schedule.ID == id {
+			schedules := make([]Schedule, 0, len(sc.schedules)-1)
+			schedules = append(schedules, sc.schedules[:idx]...)
+			schedules = append(schedules, sc.schedules[idx+1:]...)
+			return schedule, sc.setState(sc.nextID, schedules)
+		}
+	}
+	return Schedule{}, fmt.Errorf("schedule not found: %d", id)
+}
+
+// Schedules returns a copy of all schedules sorted by their ID.
+func (sc *schedulerConfig) Schedules() []Schedule {
+	sc.RLock()
+	defer sc.RUnlock()
+	result := make([]Schedule, len(sc.schedules))
+	copy(result, sc.schedules)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// Due returns all schedules that need to be executed at the minute of t.
+func (sc *schedulerConfig) Due(t time.Time) []Schedule {
+	sc.RLock()
+	defer sc.RUnlock()
+	t = t.In(sc.location)
+	result := make([]Schedule, 0)
+	for _, schedule := range sc.schedules {
+		if schedule.schedule.Matches(t) {
+			result = append(result, schedule)
+		}
+	}
+	return result
+}
+
+func (sc *schedulerConfig) Name() string {
+	return "scheduler"
+}
+
+func (sc *schedulerConfig) Options() configo.Options {
+	return configo.Options{
+		{
+			Key:             "SCHEDULER_TIMEZONE",
+			Description:     "The IANA time zone that the cron expressions of the schedules are evaluated in, e.g. Europe/Berlin",
+			DefaultValue:    "Local",
+			ParseFunction:   parsers.String(&sc.timezone),
+			UnparseFunction: unparsers.String(&sc.timezone),
+		},
+	}
+}
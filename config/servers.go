@@ -0,0 +1,431 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	configo "github.com/jxsl13/simple-configo"
+	"github.com/jxsl13/simple-configo/unparsers"
+)
+
+var (
+	nameRegex               = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+	errNameMsg              = "names must only contain alphanumeric characters, underscores and dashes"
+	serverPairDelimiter     = ","
+	serverKeyValueDelimiter = "->"
+	// members of a group are separated by whitespaces
+	groupMemberDelimiter = " "
+
+	serversStateName = "servers"
+)
+
+// serverState is the persisted representation of aliases and groups
+type serverState struct {
+	Aliases map[string]string   `json:"aliases"`
+	Groups  map[string][]string `json:"groups"`
+}
+
+func newServerConfig() *serverConfig {
+	return &serverConfig{
+		aliases: make(map[string]string),
+		groups:  make(map[string]string),
+	}
+}
+
+// serverConfig allows to reference servers via human readable aliases
+// and to reference multiple servers at once via groups.
+type serverConfig struct {
+	// aliases maps an alias to an econ address as it was configured
+	aliases map[string]string
+	// groups maps a group name to a whitespace separated list of aliases or econ addresses
+	// as they were configured
+	groups map[string]string
+
+	sync.RWMutex
+}
+
+func (sc *serverConfig) PostParse() error {
+	sc.Lock()
+	defer sc.Unlock()
+
+	err := sc.loadState()
+	if err != nil {
+		return err
+	}
+
+	for alias, addr := range sc.aliases {
+		if err := validateName(alias); err != nil {
+			return fmt.Errorf("invalid alias %s: %w", alias, err)
+		}
+		if _, err := NormalizeAddress(addr); err != nil {
+			return fmt.Errorf("invalid address of alias %s: %w", alias, err)
+		}
+		sc.aliases[alias] = strings.TrimSpace(addr)
+	}
+
+	for group, members := range sc.groups {
+		if err := validateName(group); err != nil {
+			return fmt.Errorf("invalid group %s: %w", group, err)
+		}
+		if _, found := sc.aliases[group]; found {
+			return fmt.Errorf("group %s has the same name as an alias", group)
+		}
+		for _, member := range strings.Fields(members) {
+			if _, isAlias := sc.aliases[member]; isAlias {
+				continue
+			}
+			if _, err := NormalizeAddress(member); err != nil {
+				return fmt.Errorf("invalid member of group %s: %w", group, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (sc *serverConfig) Close() error {
+	return nil
+}
+
+// loadState replaces the aliases and groups from the environment with the ones
+// of the state file, if it exists. expects the lock to be held
+func (sc *serverConfig) loadState() error {
+	state := serverState{}
+	found, err := loadState(serversStateName, &state)
+	if err != nil || !found {
+		return err
+	}
+	sc.aliases = make(map[string]string, len(state.Aliases))
+	for alias, addr := range state.Aliases {
+		sc.aliases[alias] = addr
+	}
+	sc.groups = make(map[string]string, len(state.Groups))
+	for group, members := range state.Groups {
+		sc.groups[group] = strings.Join(members, groupMemberDelimiter)
+	}
+	return nil
+}
+
+// setState persists the passed aliases and groups immediately and replaces the current ones
+// only if they were persisted successfully, expects the lock to be held
+func (sc *serverConfig) setState(aliases, groups map[string]string) error {
+	state := serverState{
+		Aliases: make(map[string]string, len(aliases)),
+		Groups:  make(map[string][]string, len(groups)),
+	}
+	for alias, addr := range aliases {
+		state.Aliases[alias] = addr
+	}
+	for group, members := range groups {
+		state.Groups[group] = strings.Fields(members)
+	}
+	err := saveState(serversStateName, state)
+	if err != nil {
+		return err
+	}
+	sc.aliases = aliases
+	sc.groups = groups
+	return nil
+}
+
+func copyMap(m map[string]string) map[string]string {
+	result := make(map[string]string, len(m))
+	for key, value := range m {
+		result[key] = value
+	}
+	return result
+}
+
+func validateName(name string) error {
+	if !nameRegex.MatchString(name) {
+		return errors.New(errNameMsg)
+	}
+	return nil
+}
+
+// resolveMember resolves either an alias or an econ address to the normalized econ address
+// expects the lock to be held.
+func (sc *serverConfig) resolveMember(member string) (string, error) {
+	if addr, found := sc.aliases[member]; found {
+		member = addr
+	}
+	addr, err := NormalizeAddress(member)
+	if err != nil {
+		return "", fmt.Errorf("unknown alias or invalid address: %s", member)
+	}
+	return addr, nil
+}
+
+// Resolve resolves an alias, a group or an econ address to a sorted list of unique econ addresses.
+func (sc *serverConfig) Resolve(target string) ([]string, error) {
+	sc.RLock()
+	defer sc.RUnlock()
+
+	members, found := sc.groups[target]
+	if !found {
+		addr, err := sc.resolveMember(target)
+		if err != nil {
+			return nil, fmt.Errorf("unknown target: %s", target)
+		}
+		return []string{addr}, nil
+	}
+
+	unique := make(map[string]bool)
+	for _, member := range strings.Fields(members) {
+		addr, err := sc.resolveMember(member)
+		if err != nil {
+			return nil, err
+		}
+		unique[addr] = true
+	}
+
+	addrs := make([]string, 0, len(unique))
+	for addr := range unique {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs, nil
+}
+
+// ResolveServer resolves either an alias or an econ address, but not a group.
+func (sc *serverConfig) ResolveServer(target string) (string, error) {
+	sc.RLock()
+	defer sc.RUnlock()
+	if _, found := sc.groups[target]; found {
+		return "", fmt.Errorf("%s is a group, expected a single server", target)
+	}
+	return sc.resolveMember(target)
+}
+
+// Alias returns the alias of the passed econ address or the econ address itself,
+// in case it has no alias.
+func (sc *serverConfig) Alias(econAddr string) string {
+	if normalized, err := NormalizeAddress(econAddr); err == nil {
+		econAddr = normalized
+	}
+	sc.RLock()
+	defer sc.RUnlock()
+	for alias, addr := range sc.aliases {
+		if normalized, err := NormalizeAddress(addr); err == nil && normalized == econAddr {
+			return alias
+		}
+	}
+	return econAddr
+}
+
+// queueName returns the econ address of the server as it was configured in an alias or a group.
+func (sc *serverConfig) queueName(econAddr string) (string, bool) {
+	econAddr, err := NormalizeAddress(econAddr)
+	if err != nil {
+		return "", false
+	}
+	sc.RLock()
+	defer sc.RUnlock()
+
+	candidates := make([]string, 0, len(sc.aliases))
+	for _, addr := range sc.aliases {
+		candidates = append(candidates, addr)
+	}
+	for _, members := range sc.groups {
+		candidates = append(candidates, strings.Fields(members)...)
+	}
+	// the result must not depend on the iteration order of the maps
+	sort.Strings(candidates)
+	for _, candidate := range candidates {
+		if normalized, err := NormalizeAddress(candidate); err == nil && normalized == econAddr {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// SetAlias creates or updates an alias for the passed econ address.
+func (sc *serverConfig) SetAlias(alias, econAddr string) error {
+	if err := validateName(alias); err != nil {
+		return err
+	}
+	if _, err := NormalizeAddress(econAddr); err != nil {
+		return err
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+	if _, found := sc.groups[alias]; found {
+		return fmt.Errorf("a group with the name %s already exists", alias)
+	}
+	aliases := copyMap(sc.aliases)
+	aliases[alias] = strings.TrimSpace(econAddr)
+	return sc.setState(aliases, sc.groups)
+}
+
+// RemoveAlias removes an alias that is not used by any group.
+func (sc *serverConfig) RemoveAlias(alias string) (string, error) {
+	sc.Lock()
+	defer sc.Unlock()
+
+	addr, found := sc.aliases[alias]
+	if !found {
+		return "", fmt.Errorf("alias not found: %s", alias)
+	}
+	for group, members := range sc.groups {
+		for _, member := range strings.Fields(members) {
+			if member == alias {
+				return "", fmt.Errorf("alias %s is still used by group %s", alias, group)
+			}
+		}
+	}
+	aliases := copyMap(sc.aliases)
+	delete(aliases, alias)
+	return addr, sc.setState(aliases, sc.groups)
+}
+
+// Aliases returns a copy of the alias to econ address mapping.
+func (sc *serverConfig) Aliases() map[string]string {
+	sc.RLock()
+	defer sc.RUnlock()
+	return copyMap(sc.aliases)
+}
+
+// AddToGroup adds the members to the group, the group is created if it does not exist.
+func (sc *serverConfig) AddToGroup(group string, members ...string) error {
+	if err := validateName(group); err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return fmt.Errorf("no members passed")
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	if _, found := sc.aliases[group]; found {
+		return fmt.Errorf("an alias with the name %s already exists", group)
+	}
+
+	current := strings.Fields(sc.groups[group])
+	for _, member := range members {
+		if _, isAlias := sc.aliases[member]; !isAlias {
+			// econ addresses are stored as they were configured
+			if _, err := NormalizeAddress(member); err != nil {
+				return fmt.Errorf("unknown alias or invalid address: %s", member)
+			}
+			member = strings.TrimSpace(member)
+		}
+		if !containsMember(current, member) {
+			current = append(current, member)
+		}
+	}
+	groups := copyMap(sc.groups)
+	groups[group] = strings.Join(current, groupMemberDelimiter)
+	return sc.setState(sc.aliases, groups)
+}
+
+// RemoveFromGroup removes the passed members from the group.
+// If no members are passed or the group is empty afterwards, the group is removed.
+func (sc *serverConfig) RemoveFromGroup(group string, members ...string) error {
+	sc.Lock()
+	defer sc.Unlock()
+
+	current, found := sc.groups[group]
+	if !found {
+		return fmt.Errorf("group not found: %s", group)
+	}
+
+	remaining := make([]string, 0)
+	for _, member := range strings.Fields(current) {
+		if len(members) > 0 && !containsMember(members, member) {
+			remaining = append(remaining, member)
+		}
+	}
+
+	groups := copyMap(sc.groups)
+	if len(remaining) == 0 {
+		delete(groups, group)
+	} else {
+		groups[group] = strings.Join(remaining, groupMemberDelimiter)
+	}
+	return sc.setState(sc.aliases, groups)
+}
+
+// Groups returns a copy of the group to members mapping.
+func (sc *serverConfig) Groups() map[string][]string {
+	sc.RLock()
+	defer sc.RUnlock()
+	result := make(map[string][]string, len(sc.groups))
+	for group, members := range sc.groups {
+		result[group] = strings.Fields(members)
+	}
+	return result
+}
+
+// Addresses returns the sorted econ addresses of all aliases and group members.
+func (sc *serverConfig) Addresses() []string {
+	sc.RLock()
+	defer sc.RUnlock()
+	addrs := make([]string, 0, len(sc.aliases))
+	for _, addr := range sc.aliases {
+		if addr, err := NormalizeAddress(addr); err == nil && !contains(addrs, addr) {
+			addrs = append(addrs, addr)
+		}
+	}
+	for _, members := range sc.groups {
+		for _, member := range strings.Fields(members) {
+			if addr, err := sc.resolveMember(member); err == nil && !contains(addrs, addr) {
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsMember returns true if the list contains the alias or an econ address that
+// normalizes to the same address as the member.
+func containsMember(list []string, member string) bool {
+	if contains(list, member) {
+		return true
+	}
+	normalized, err := NormalizeAddress(member)
+	if err != nil {
+		return false
+	}
+	for _, v := range list {
+		if addr, err := NormalizeAddress(v); err == nil && addr == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+func (sc *serverConfig) Name() string {
+	return "servers"
+}
+
+func (sc *serverConfig) Options() configo.Options {
+	return configo.Options{
+		{
+			Key:             "SERVER_ALIASES",
+			Description:     "Named aliases of econ addresses: alias->ip:econ_port,alias2->ip:econ_port2",
+			ParseFunction:   optionalMap(&sc.aliases, &serverPairDelimiter, &serverKeyValueDelimiter),
+			UnparseFunction: unparsers.Map(&sc.aliases, &serverPairDelimiter, &serverKeyValueDelimiter),
+		},
+		{
+			Key:             "SERVER_GROUPS",
+			Description:     "Named groups of whitespace separated aliases or econ addresses: group->alias alias2,group2->alias3 ip:econ_port",
+			ParseFunction:   optionalMap(&sc.groups, &serverPairDelimiter, &serverKeyValueDelimiter),
+			UnparseFunction: unparsers.Map(&sc.groups, &serverPairDelimiter, &serverKeyValueDelimiter),
+		},
+	}
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// State files contain modifications that are done at runtime, e.g. via discord commands.
+// They are written immediately after every modification in order to survive crashes
+// and take precedence over the corresponding values of the env file or the environment.
+
+var (
+	// guards all state file writes
+	stateMu sync.Mutex
+)
+
+func statePath(name string) string {
+	return filepath.Join(Modules().StatePath(), name+".json")
+}
+
+// loadState unmarshals the named state file into v.
+// found is false in case the state file does not exist.
+func loadState(name string, v interface{}) (found bool, err error) {
+	data, err := os.ReadFile(statePath(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	err = json.Unmarshal(data, v)
+	if err != nil {
+		return false, fmt.Errorf("invalid state file %s: %w", statePath(name), err)
+	}
+	return true, nil
+}
+
+// saveState atomically replaces the named state file with the json representation of v.
+// The data is written to a temporary file first which then replaces the state file.
+func saveState(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	stateMu.Lock()
+	defer stateMu.Unlock()
+
+	filePath := statePath(name)
+	dir := filepath.Dir(filePath)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+name+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	// noop after a successful rename
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Sync()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// useStatePath stores the state files of the test in a temporary folder.
+func useStatePath(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	moduleCfg = &moduleConfig{statePath: dir}
+	return dir
+}
+
+func TestSaveAndLoadState(t *testing.T) {
+	dir := useStatePath(t)
+
+	type state struct {
+		Names []string `json:"names"`
+	}
+	tests := []struct {
+		name  string
+		state state
+	}{
+		{"empty", state{}},
+		{"single", state{Names: []string{"a"}}},
+		{"multiple", state{Names: []string{"a", "b", "c"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := saveState(tt.name, tt.state)
+			if err != nil {
+				t.Fatalf("saveState() unexpected error: %v", err)
+			}
+			got := state{}
+			found, err := loadState(tt.name, &got)
+			if err != nil || !found {
+				t.Fatalf("loadState() = %v, %v, want true, nil", found, err)
+			}
+			if !reflect.DeepEqual(got, tt.state) {
+				t.Errorf("loadState() = %v, want %v", got, tt.state)
+			}
+		})
+	}
+
+	// the temporary files are renamed or removed
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(tests) {
+		t.Errorf("state folder contains %d files, want %d", len(files), len(tests))
+	}
+}
+
+func TestLoadState(t *testing.T) {
+	dir := useStatePath(t)
+	err := os.WriteFile(filepath.Join(dir, "invalid.json"), []byte("{"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		wantFound bool
+		wantErr   bool
+	}{
+		{"missing", false, false},
+		{"invalid", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := make(map[string]string)
+			found, err := loadState(tt.name, &v)
+			if found != tt.wantFound || (err != nil) != tt.wantErr {
+				t.Errorf("loadState(%s) = %v, %v, want %v, wantErr %v", tt.name, found, err, tt.wantFound, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestServerStatePrecedence(t *testing.T) {
+	useStatePath(t)
+
+	err := saveState(serversStateName, serverState{
+		Aliases: map[string]string{"ctf": "127.0.0.1:9303"},
+		Groups:  map[string][]string{"all": {"ctf"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// parsed from the environment
+	sc := newServerConfig()
+	sc.aliases = map[string]string{"ctf": "127.0.0.1:8303", "dm": "127.0.0.1:8304"}
+	err = sc.PostParse()
+	if err != nil {
+		t.Fatalf("PostParse() unexpected error: %v", err)
+	}
+
+	wantAliases := map[string]string{"ctf": "127.0.0.1:9303"}
+	if got := sc.Aliases(); !reflect.DeepEqual(got, wantAliases) {
+		t.Errorf("Aliases() = %v, want %v", got, wantAliases)
+	}
+	wantGroups := map[string][]string{"all": {"ctf"}}
+	if got := sc.Groups(); !reflect.DeepEqual(got, wantGroups) {
+		t.Errorf("Groups() = %v, want %v", got, wantGroups)
+	}
+}
+
+func TestServerStateIsReplacedAfterSaving(t *testing.T) {
+	dir := useStatePath(t)
+	sc := newServerConfig()
+
+	tests := []struct {
+		name    string
+		modify  func() error
+		wantErr bool
+		want    map[string]string
+	}{
+		{"set alias", func() error { return sc.SetAlias("ctf", "127.0.0.1:8303") }, false, map[string]string{"ctf": "127.0.0.1:8303"}},
+		{"unsaved alias", func() error {
+			// the state folder cannot be created
+			moduleCfg = &moduleConfig{statePath: filepath.Join(dir, "servers.json", "nested")}
+			return sc.SetAlias("dm", "127.0.0.1:8304")
+		}, true, map[string]string{"ctf": "127.0.0.1:8303"}},
+		{"unsaved removal", func() error {
+			_, err := sc.RemoveAlias("ctf")
+			return err
+		}, true, map[string]string{"ctf": "127.0.0.1:8303"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.modify()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := sc.Aliases(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Aliases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
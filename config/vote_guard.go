@@ -0,0 +1,296 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/console"
+	configo "github.com/jxsl13/simple-configo"
+	"github.com/jxsl13/simple-configo/parsers"
+	"github.com/jxsl13/simple-configo/unparsers"
+)
+
+const voteGuardRequestorID = "vote-guard"
+
+type voteGuardConfig struct {
+	protectedNames  []string
+	protectedClans  []string
+	protectedIPStrs []string
+	protectedIPNets []*net.IPNet
+	minJoinAge      time.Duration
+	maxVotes        int
+	voteWindow      time.Duration
+	abortCommand    string
+	sayCommand      string
+
+	// target@option_pattern -> player_cooldown/global_cooldown
+	optionRuleStrs map[string]string
+	optionRules    []VoteOptionRule
+
+	sync.RWMutex
+}
+
+// VoteOptionRule limits how often vote options that match the pattern may be voted
+// on the servers of the target. Every matching option has its own cooldowns, which start
+// once a vote for the option passed.
+type VoteOptionRule struct {
+	// Target is a server alias, group, econ address or * for all servers
+	Target string
+	// Pattern is a case insensitive glob pattern of the vote option, e.g. "change map*"
+	Pattern string
+	// PlayerCooldown is the time a player has to wait before voting the same option again
+	PlayerCooldown time.Duration
+	// GlobalCooldown is the time all players have to wait before voting the same option again
+	GlobalCooldown time.Duration
+}
+
+// Key identifies the rule
+func (r VoteOptionRule) Key() string {
+	return r.Target + "@" + r.Pattern
+}
+
+// parseVoteOptionRule parses a rule with the format target@option_pattern -> player_cooldown/global_cooldown
+func parseVoteOptionRule(key, value string) (VoteOptionRule, error) {
+	parts := strings.SplitN(key, "@", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return VoteOptionRule{}, fmt.Errorf("invalid vote option rule %s: expected target@option_pattern", key)
+	}
+	rule := VoteOptionRule{
+		Target:  strings.TrimSpace(parts[0]),
+		Pattern: strings.ToLower(strings.TrimSpace(parts[1])),
+	}
+	if _, err := path.Match(rule.Pattern, ""); err != nil {
+		return VoteOptionRule{}, fmt.Errorf("invalid option pattern of vote option rule %s: %w", key, err)
+	}
+	if rule.Target != "*" {
+		if _, err := Servers().Resolve(rule.Target); err != nil {
+			return VoteOptionRule{}, fmt.Errorf("invalid target of vote option rule %s: %w", key, err)
+		}
+	}
+
+	cooldowns := strings.SplitN(value, "/", 2)
+	if len(cooldowns) != 2 {
+		return VoteOptionRule{}, fmt.Errorf("invalid cooldowns of vote option rule %s: expected player_cooldown/global_cooldown", key)
+	}
+	var err error
+	if rule.PlayerCooldown, err = time.ParseDuration(strings.TrimSpace(cooldowns[0])); err != nil {
+		return VoteOptionRule{}, fmt.Errorf("invalid player cooldown of vote option rule %s: %w", key, err)
+	}
+	if rule.GlobalCooldown, err = time.ParseDuration(strings.TrimSpace(cooldowns[1])); err != nil {
+		return VoteOptionRule{}, fmt.Errorf("invalid global cooldown of vote option rule %s: %w", key, err)
+	}
+	return rule, nil
+}
+
+func (vgc *voteGuardConfig) PostParse() error {
+	vgc.Lock()
+	defer vgc.Unlock()
+
+	vgc.protectedIPNets = make([]*net.IPNet, 0, len(vgc.protectedIPStrs))
+	for _, ipStr := range vgc.protectedIPStrs {
+		ipNet, err := parseIPNet(strings.TrimSpace(ipStr))
+		if err != nil {
+			return fmt.Errorf("invalid protected IP: %w", err)
+		}
+		vgc.protectedIPNets = append(vgc.protectedIPNets, ipNet)
+	}
+	if strings.TrimSpace(vgc.abortCommand) == "" {
+		return fmt.Errorf("VOTE_GUARD_ABORT_COMMAND must not be empty")
+	}
+
+	vgc.optionRules = make([]VoteOptionRule, 0, len(vgc.optionRuleStrs))
+	for key, value := range vgc.optionRuleStrs {
+		rule, err := parseVoteOptionRule(key, value)
+		if err != nil {
+			return err
+		}
+		vgc.optionRules = append(vgc.optionRules, rule)
+	}
+	sort.Slice(vgc.optionRules, func(i, j int) bool {
+		return vgc.optionRules[i].Key() < vgc.optionRules[j].Key()
+	})
+	return nil
+}
+
+func (vgc *voteGuardConfig) Close() error {
+	return nil
+}
+
+// parseIPNet parses either a single IP or a CIDR range.
+func parseIPNet(value string) (*net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, ipNet, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		return ipNet, nil
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("%s is neither an IP nor a CIDR range", value)
+	}
+	bits := 8 * net.IPv6len
+	if ip4 := ip.To4(); ip4 != nil {
+		ip, bits = ip4, 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// IsProtected returns a reason if the player must not be the target of a vote.
+func (vgc *voteGuardConfig) IsProtected(name, clan, ip string) (string, bool) {
+	vgc.RLock()
+	defer vgc.RUnlock()
+
+	for _, protected := range vgc.protectedNames {
+		if strings.EqualFold(protected, name) {
+			return fmt.Sprintf("%s is a protected player", name), true
+		}
+	}
+	for _, protected := range vgc.protectedClans {
+		if clan != "" && strings.EqualFold(protected, clan) {
+			return fmt.Sprintf("the clan %s is protected", clan), true
+		}
+	}
+	if parsed := net.ParseIP(ip); parsed != nil {
+		for _, ipNet := range vgc.protectedIPNets {
+			if ipNet.Contains(parsed) {
+				return fmt.Sprintf("%s has a protected IP", name), true
+			}
+		}
+	}
+	return "", false
+}
+
+// MinJoinAge is the time a player must be connected before being allowed to start a vote.
+func (vgc *voteGuardConfig) MinJoinAge() time.Duration {
+	vgc.RLock()
+	defer vgc.RUnlock()
+	return vgc.minJoinAge
+}
+
+// VoteRate returns the maximum number of votes a player may start within the returned window.
+func (vgc *voteGuardConfig) VoteRate() (int, time.Duration) {
+	vgc.RLock()
+	defer vgc.RUnlock()
+	return vgc.maxVotes, vgc.voteWindow
+}
+
+// OptionRules returns the rules that apply to the vote option on the passed server.
+func (vgc *voteGuardConfig) OptionRules(econAddr, option string) []VoteOptionRule {
+	vgc.RLock()
+	defer vgc.RUnlock()
+
+	option = strings.ToLower(strings.TrimSpace(option))
+	result := make([]VoteOptionRule, 0, 1)
+	for _, rule := range vgc.optionRules {
+		if matched, _ := path.Match(rule.Pattern, option); !matched {
+			continue
+		}
+		if rule.Target != "*" {
+			addrs, err := Servers().Resolve(rule.Target)
+			if err != nil || !contains(addrs, econAddr) {
+				continue
+			}
+		}
+		result = append(result, rule)
+	}
+	return result
+}
+
+// RequestAbort aborts the currently running vote on the passed server
+// and explains the reason in-game.
+func (vgc *voteGuardConfig) RequestAbort(econAddr, reason string) error {
+	vgc.RLock()
+	commands := []string{vgc.abortCommand}
+	if vgc.sayCommand != "" && reason != "" {
+		commands = append(commands, console.Replace(vgc.sayCommand, "{REASON}", reason))
+	}
+	vgc.RUnlock()
+
+	for _, command := range commands {
+		event := events.NewRequestCommandExecEvent()
+		event.Timestamp = time.Now().Format("2006-01-02 15:04:05")
+		event.Requestor = voteGuardRequestorID
+		event.EventSource = voteGuardRequestorID
+		event.Command = command
+		err := Broker().Publisher().Publish("", QueueName(econAddr), event.Marshal())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (vgc *voteGuardConfig) Name() string {
+	return "vote-guard"
+}
+
+func (vgc *voteGuardConfig) Options() configo.Options {
+	return configo.Options{
+		{
+			Key:             "VOTE_GUARD_PROTECTED_NAMES",
+			Description:     "Comma separated list of player names that cannot be kick- or specvoted, e.g. the names of the moderators",
+			ParseFunction:   parsers.List(&vgc.protectedNames, &serverPairDelimiter),
+			UnparseFunction: unparsers.List(&vgc.protectedNames, &serverPairDelimiter),
+		},
+		{
+			Key:             "VOTE_GUARD_PROTECTED_CLANS",
+			Description:     "Comma separated list of clan tags whose members cannot be kick- or specvoted",
+			ParseFunction:   parsers.List(&vgc.protectedClans, &serverPairDelimiter),
+			UnparseFunction: unparsers.List(&vgc.protectedClans, &serverPairDelimiter),
+		},
+		{
+			Key:             "VOTE_GUARD_PROTECTED_IPS",
+			Description:     "Comma separated list of IPs or CIDR ranges whose players cannot be kick- or specvoted",
+			ParseFunction:   parsers.List(&vgc.protectedIPStrs, &serverPairDelimiter),
+			UnparseFunction: unparsers.List(&vgc.protectedIPStrs, &serverPairDelimiter),
+		},
+		{
+			Key:             "VOTE_GUARD_MIN_JOIN_AGE",
+			Description:     "Votes of players that joined less than this duration ago are aborted, 0 disables the check (e.g. 30s, 1m)",
+			DefaultValue:    "30s",
+			ParseFunction:   parsers.Duration(&vgc.minJoinAge),
+			UnparseFunction: unparsers.Duration(&vgc.minJoinAge),
+		},
+		{
+			Key:             "VOTE_GUARD_MAX_VOTES",
+			Description:     "The number of votes a player may start within VOTE_GUARD_VOTE_WINDOW, 0 disables the check",
+			DefaultValue:    "3",
+			ParseFunction:   parsers.RangesInt(&vgc.maxVotes, 0, 1000),
+			UnparseFunction: unparsers.Int(&vgc.maxVotes),
+		},
+		{
+			Key:             "VOTE_GUARD_VOTE_WINDOW",
+			Description:     "The time window of VOTE_GUARD_MAX_VOTES (e.g. 5m, 1h)",
+			DefaultValue:    "10m",
+			ParseFunction:   parsers.Duration(&vgc.voteWindow),
+			UnparseFunction: unparsers.Duration(&vgc.voteWindow),
+		},
+		{
+			Key:             "VOTE_GUARD_ABORT_COMMAND",
+			Description:     "The econ command that aborts the currently running vote",
+			DefaultValue:    "vote no",
+			ParseFunction:   parsers.String(&vgc.abortCommand),
+			UnparseFunction: unparsers.String(&vgc.abortCommand),
+		},
+		{
+			Key:             "VOTE_GUARD_SAY_COMMAND",
+			Description:     "The econ command that explains the reason of an aborted vote in-game, the variable {REASON} is replaced with the escaped or quoted reason, empty disables the explanation",
+			DefaultValue:    `say {REASON}`,
+			ParseFunction:   parsers.String(&vgc.sayCommand),
+			UnparseFunction: unparsers.String(&vgc.sayCommand),
+		},
+		{
+			Key:             "VOTE_OPTION_RULES",
+			Description:     "Cooldowns of vote options per server: target@option_pattern->player_cooldown/global_cooldown, e.g. *@change map*->10m/2m,ctf1@restart->1h/30m with * targeting all servers. Every matching option has its own cooldowns, which start once a vote for it passed",
+			ParseFunction:   optionalMap(&vgc.optionRuleStrs, &serverPairDelimiter, &serverKeyValueDelimiter),
+			UnparseFunction: unparsers.Map(&vgc.optionRuleStrs, &serverPairDelimiter, &serverKeyValueDelimiter),
+		},
+	}
+}
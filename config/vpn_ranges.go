@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/ipranges"
+	"github.com/go-redis/redis"
+	"github.com/jxsl13/goripr"
+)
+
+// RangeDatabase stores IP ranges with their ban reason.
+// IPv4 ranges are stored in the goripr database, IPv6 ranges in a separate store.
+type RangeDatabase struct {
+	v4 *goripr.Client
+	v6 *ipv6RangeStore
+}
+
+func newRangeDatabase(options *redis.Options) (*RangeDatabase, error) {
+	v4, err := goripr.NewClient(goripr.Options{
+		Addr:     options.Addr,
+		Password: options.Password,
+		DB:       options.DB,
+	})
+	if err != nil {
+		return nil, err
+	}
+	v6, err := newIPv6RangeStore(options)
+	if err != nil {
+		v4.Close()
+		return nil, err
+	}
+	return &RangeDatabase{v4: v4, v6: v6}, nil
+}
+
+// Insert adds an IPv4 or IPv6 address, CIDR or range <IP> - <IP> with its reason.
+func (rdb *RangeDatabase) Insert(ipRange, reason string) error {
+	r, err := ipranges.Parse(ipRange)
+	if err != nil {
+		return err
+	}
+	if r.IPv6() {
+		return rdb.v6.Insert(ipRange, reason)
+	}
+	return rdb.v4.Insert(r.String(), reason)
+}
+
+// InsertBatch adds the ranges in the passed order, like calling Insert for every range.
+func (rdb *RangeDatabase) InsertBatch(entries []ipranges.Entry) error {
+	v6 := make([]ipranges.Entry, 0)
+	for _, entry := range entries {
+		if entry.IPv6() {
+			v6 = append(v6, entry)
+			continue
+		}
+		err := rdb.v4.Insert(entry.String(), entry.Reason)
+		if err != nil {
+			return fmt.Errorf("failed to add range %s: %w", entry.Range, err)
+		}
+	}
+	return rdb.v6.InsertBatch(v6)
+}
+
+// Remove removes an IPv4 or IPv6 address, CIDR or range <IP> - <IP>.
+func (rdb *RangeDatabase) Remove(ipRange string) error {
+	r, err := ipranges.Parse(ipRange)
+	if err != nil {
+		return err
+	}
+	if r.IPv6() {
+		return rdb.v6.Remove(ipRange)
+	}
+	return rdb.v4.Remove(r.String())
+}
+
+// Find returns the reason of the range that contains the IPv4 or IPv6 address,
+// goripr.ErrIPNotFound is returned if no range contains the IP.
+func (rdb *RangeDatabase) Find(ip string) (string, error) {
+	reason, _, err := rdb.FindSource(ip)
+	return reason, err
+}
+
+// FindSource returns the reason and the blacklist range that contains the IPv4 or IPv6 address.
+// The database only contains the parts of the blacklist ranges that are left after applying
+// overlapping ranges and the whitelists, so the blacklist range is stored along with the reason.
+// The range is empty for values that were inserted without it.
+func (rdb *RangeDatabase) FindSource(ip string) (reason, ipRange string, err error) {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return "", "", goripr.ErrInvalidIP
+	}
+	var value string
+	if isIPv6(parsed) {
+		value, err = rdb.v6.Find(parsed)
+	} else {
+		value, err = rdb.v4.Find(parsed.To4().String())
+	}
+	if err != nil {
+		return "", "", err
+	}
+	ipRange, reason = splitSource(value)
+	return reason, ipRange, nil
+}
+
+// sourceSeparator separates the blacklist range from the reason of a stored value.
+// Reasons are sanitized and thus never contain control characters.
+const sourceSeparator = "\x1f"
+
+// joinSource returns the value that is stored for a part of the blacklist range.
+func joinSource(ipRange, reason string) string {
+	return ipRange + sourceSeparator + reason
+}
+
+// splitSource is the inverse of joinSource, values without a range are returned as reason.
+func splitSource(value string) (ipRange, reason string) {
+	idx := strings.Index(value, sourceSeparator)
+	if idx < 0 {
+		return "", value
+	}
+	return value[:idx], value[idx+len(sourceSeparator):]
+}
+
+// validateRange returns goripr.ErrInvalidRange if the passed value is neither
+// an IPv4 or IPv6 address, CIDR or range <IP> - <IP>.
+func validateRange(ipRange string) error {
+	_, err := ipranges.Parse(ipRange)
+	return err
+}
+
+func (rdb *RangeDatabase) Close() error {
+	err := rdb.v6.Close()
+	if err != nil {
+		rdb.v4.Close()
+		return err
+	}
+	return rdb.v4.Close()
+}
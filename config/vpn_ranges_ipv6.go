@@ -0,0 +1,199 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/ipranges"
+	"github.com/go-redis/redis"
+	"github.com/jxsl13/goripr"
+)
+
+// ipv6RangesKey is the redis hash that contains the IPv6 ranges: low-high -> reason
+const ipv6RangesKey = "________________IPV6_RANGES________________"
+
+func ipv6Field(entry ipranges.Entry) string {
+	return entry.Low.String() + "-" + entry.High.String()
+}
+
+// ipv6RangeStore stores disjoint IPv6 ranges, as goripr only supports IPv4.
+// The ranges are kept in memory sorted by their lower boundary and persisted in a redis hash.
+// Like goripr, inserting a range replaces the overlapping parts of previous ranges
+// and removing a range cuts it out of all overlapping ranges.
+type ipv6RangeStore struct {
+	rdb    *redis.Client
+	ranges []ipranges.Entry
+	mu     sync.RWMutex
+}
+
+func newIPv6RangeStore(options *redis.Options) (*ipv6RangeStore, error) {
+	s := &ipv6RangeStore{
+		rdb:    redis.NewClient(options),
+		ranges: make([]ipranges.Entry, 0),
+	}
+
+	stored, err := s.rdb.HGetAll(ipv6RangesKey).Result()
+	if err != nil {
+		s.rdb.Close()
+		return nil, err
+	}
+	for field, reason := range stored {
+		r, err := parseIPv6Range(field)
+		if err != nil {
+			s.rdb.Close()
+			return nil, fmt.Errorf("invalid stored IPv6 range %s: %w", field, err)
+		}
+		s.ranges = append(s.ranges, ipranges.Entry{Range: r, Reason: reason})
+	}
+	sort.Slice(s.ranges, func(i, j int) bool {
+		return bytes.Compare(s.ranges[i].Low, s.ranges[j].Low) < 0
+	})
+	return s, nil
+}
+
+func (s *ipv6RangeStore) Close() error {
+	return s.rdb.Close()
+}
+
+// Insert adds the range, overlapping parts of previous ranges are replaced.
+func (s *ipv6RangeStore) Insert(ipRange, reason string) error {
+	r, err := parseIPv6Range(ipRange)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed, added []ipranges.Entry
+	s.ranges, removed, added = ipranges.Insert(s.ranges, ipranges.Entry{Range: r, Reason: reason})
+	return s.persist(removed, added)
+}
+
+// InsertBatch adds the ranges in the passed order, like calling Insert for every range.
+// The ranges are combined at once and the redis hash is rewritten, which is considerably
+// faster for a large number of ranges.
+func (s *ipv6RangeStore) InsertBatch(entries []ipranges.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the stored ranges are disjoint, their order does not matter
+	combined := make([]ipranges.Entry, 0, len(s.ranges)+len(entries))
+	combined = append(combined, s.ranges...)
+	combined = append(combined, entries...)
+	s.ranges = ipranges.Overlay(combined)
+
+	pipe := s.rdb.TxPipeline()
+	pipe.Del(ipv6RangesKey)
+	if len(s.ranges) > 0 {
+		fields := make(map[string]interface{}, len(s.ranges))
+		for _, entry := range s.ranges {
+			fields[ipv6Field(entry)] = entry.Reason
+		}
+		pipe.HMSet(ipv6RangesKey, fields)
+	}
+	_, err := pipe.Exec()
+	return err
+}
+
+// Remove cuts the range out of all stored ranges.
+func (s *ipv6RangeStore) Remove(ipRange string) error {
+	r, err := parseIPv6Range(ipRange)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var removed, added []ipranges.Entry
+	s.ranges, removed, added = ipranges.Cut(s.ranges, r)
+	return s.persist(removed, added)
+}
+
+// Find returns the reason of the range that contains the IP.
+func (s *ipv6RangeStore) Find(ip net.IP) (string, error) {
+	found, err := s.find(ip)
+	if err != nil {
+		return "", err
+	}
+	return found.Reason, nil
+}
+
+func (s *ipv6RangeStore) find(ip net.IP) (ipranges.Entry, error) {
+	ip = ip.To16()
+	if ip == nil {
+		return ipranges.Entry{}, goripr.ErrInvalidIP
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// first range with a lower boundary above the ip
+	idx := sort.Search(len(s.ranges), func(i int) bool {
+		return bytes.Compare(s.ranges[i].Low, ip) > 0
+	})
+	if idx == 0 {
+		return ipranges.Entry{}, goripr.ErrIPNotFound
+	}
+	candidate := s.ranges[idx-1]
+	if !candidate.Contains(ip) {
+		return ipranges.Entry{}, goripr.ErrIPNotFound
+	}
+	return candidate, nil
+}
+
+// Len returns the number of stored ranges.
+func (s *ipv6RangeStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.ranges)
+}
+
+// Reset removes all ranges.
+func (s *ipv6RangeStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ranges = make([]ipranges.Entry, 0)
+	return s.rdb.Del(ipv6RangesKey).Err()
+}
+
+// persist applies the changes to the redis hash, removals are applied first.
+func (s *ipv6RangeStore) persist(removed, added []ipranges.Entry) error {
+	if len(removed) == 0 && len(added) == 0 {
+		return nil
+	}
+	pipe := s.rdb.TxPipeline()
+	for _, r := range removed {
+		pipe.HDel(ipv6RangesKey, ipv6Field(r))
+	}
+	for _, r := range added {
+		pipe.HSet(ipv6RangesKey, ipv6Field(r), r.Reason)
+	}
+	_, err := pipe.Exec()
+	return err
+}
+
+// isIPv6 returns true for IPv6 addresses, IPv4 mapped IPv6 addresses are considered to be IPv4.
+func isIPv6(ip net.IP) bool {
+	return ip != nil && ip.To4() == nil
+}
+
+// parseIPv6Range parses an IPv6 address, an IPv6 CIDR or a range <IP> - <IP>
+func parseIPv6Range(ipRange string) (ipranges.Range, error) {
+	r, err := ipranges.Parse(ipRange)
+	if err != nil {
+		return ipranges.Range{}, err
+	}
+	if !r.IPv6() {
+		return ipranges.Range{}, fmt.Errorf("%w: %s", goripr.ErrInvalidRange, ipRange)
+	}
+	return r, nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/ipranges"
+)
+
+func TestSplitSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		value      string
+		wantRange  string
+		wantReason string
+	}{
+		{"joined", joinSource("1.2.3.0/24", "VPN"), "1.2.3.0/24", "VPN"},
+		{"empty reason", joinSource("::1", ""), "::1", ""},
+		{"reason only", "VPN", "", "VPN"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ipRange, reason := splitSource(tt.value)
+			if ipRange != tt.wantRange || reason != tt.wantReason {
+				t.Errorf("splitSource(%q) = %q, %q, want %q, %q", tt.value, ipRange, reason, tt.wantRange, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestClipFilesKeepsSourceRange(t *testing.T) {
+	files := map[string]time.Time{"a.txt": {}, "b.txt": {}}
+	ranges := map[string]map[string]string{
+		"a.txt": {"10.0.0.0/8": "a"},
+		"b.txt": {"10.1.0.0/16": "b"},
+	}
+	fileRanges := func(path string) (map[string]string, error) {
+		return ranges[path], nil
+	}
+	entries, err := clipFiles(ipranges.NewSet([]ipranges.Range{mustParseRange("10.1.2.0/24")}), fileRanges, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// later entries overwrite earlier ones in the database
+	var stored []ipranges.Entry
+	for _, entry := range entries {
+		stored, _, _ = ipranges.Insert(stored, entry)
+	}
+
+	tests := []struct {
+		ip         string
+		wantRange  string
+		wantReason string
+	}{
+		{"10.1.2.3", "10.1.0.0/16", "b"},
+		{"10.1.3.3", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			ipRange, reason := "", ""
+			for _, entry := range stored {
+				if entry.Contains(net.ParseIP(tt.ip)) {
+					ipRange, reason = splitSource(entry.Reason)
+				}
+			}
+			if ipRange != tt.wantRange || reason != tt.wantReason {
+				t.Errorf("%s = %q, %q, want %q, %q", tt.ip, ipRange, reason, tt.wantRange, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+// Package console contains helpers that construct arguments of Teeworlds console commands
+// from untrusted input.
+package console
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// is thread safe/goroutine safe
+	escapeReplacer = strings.NewReplacer(
+		"\\", "\\\\",
+		"\"", "\\\"",
+	)
+)
+
+// Escape escapes backslashes and quotes, so that the text can be put into a quoted
+// console argument, and replaces line breaks as well as other control characters with spaces.
+// Trailing backslashes are removed, as the console does not treat an escaped backslash
+// in front of the closing quote as escaped and would skip the closing quote instead.
+func Escape(text string) string {
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, text)
+	return escapeReplacer.Replace(strings.TrimRight(text, "\\"))
+}
+
+// Quote escapes the text and wraps it in quotes, the result is a single console argument.
+// Semicolons inside of the quotes do not separate commands.
+func Quote(text string) string {
+	return "\"" + Escape(text) + "\""
+}
+
+// Replace replaces every occurrence of the variable in the command line with the text, so that the text
+// is part of a single argument. The text is escaped if the variable is part of a quoted argument
+// and quoted otherwise.
+func Replace(line, variable, text string) string {
+	return ReplaceFunc(line, variable, func(inQuotes bool) string {
+		if inQuotes {
+			return Escape(text)
+		}
+		return Quote(text)
+	})
+}
+
+// ReplaceFunc replaces every occurrence of the variable in the command line with the result of
+// the replacement, which is passed whether the variable is part of a quoted argument.
+func ReplaceFunc(line, variable string, replacement func(inQuotes bool) string) string {
+	if variable == "" {
+		return line
+	}
+	pattern := regexp.MustCompile(regexp.QuoteMeta(variable))
+	return ReplaceAllFunc(line, pattern, func(_ string, inQuotes bool) string {
+		return replacement(inQuotes)
+	})
+}
+
+// ReplaceAllFunc replaces all matches of the pattern in the command line with the result of the
+// replacement, which is passed the match and whether it is part of a quoted argument.
+// Like the Teeworlds console, a quote is escaped by a directly preceding backslash. A space is put in
+// between in case the replacement would start with a quote that is escaped by the command line.
+func ReplaceAllFunc(line string, pattern *regexp.Regexp, replacement func(match string, inQuotes bool) string) string {
+	var sb strings.Builder
+	inQuotes := false
+	last := 0
+	for _, match := range pattern.FindAllStringIndex(line, -1) {
+		for idx := last; idx < match[0]; idx++ {
+			if line[idx] == '"' && (idx == 0 || line[idx-1] != '\\') {
+				inQuotes = !inQuotes
+			}
+		}
+		sb.WriteString(line[last:match[0]])
+
+		replaced := replacement(line[match[0]:match[1]], inQuotes)
+		if match[0] > 0 && line[match[0]-1] == '\\' && strings.HasPrefix(replaced, "\"") {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(replaced)
+		last = match[1]
+	}
+	sb.WriteString(line[last:])
+	return sb.String()
+}
+
+// Sanitize removes semicolons, quotes and comment signs and replaces line breaks as well as other
+// control characters with spaces, so that untrusted text can be put into an unquoted console argument
+// without starting another command or a comment. Consecutive whitespace and trailing backslashes
+// are removed, the latter would escape a following quote.
+func Sanitize(text string) string {
+	text = strings.Map(func(r rune) rune {
+		switch {
+		case r == ';' || r == '"' || r == '#':
+			return -1
+		case unicode.IsControl(r):
+			return ' '
+		}
+		return r
+	}, text)
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.TrimSpace(strings.TrimRight(text, "\\"))
+}
+
+// Statements splits a command line into the ; separated statements that are executed by the
+// Teeworlds console (CConsole::ExecuteLineStroked). Semicolons within quoted arguments do not
+// separate statements. A backslash only escapes a directly following quote, inside as well as
+// outside of quoted arguments, so an escaped backslash in front of a quote still escapes the quote.
+// An unquoted # starts a comment, nothing after it is executed.
+func Statements(line string) []string {
+	statements := make([]string, 0, 1)
+	inQuotes := false
+	start := 0
+	for idx := 0; idx < len(line); idx++ {
+		switch line[idx] {
+		case '\\':
+			if idx+1 < len(line) && line[idx+1] == '"' {
+				idx++
+			}
+		case '"':
+			inQuotes = !inQuotes
+		case ';':
+			if !inQuotes {
+				statements = append(statements, line[start:idx])
+				start = idx + 1
+			}
+		case '#':
+			if !inQuotes {
+				return append(statements, line[start:idx])
+			}
+		}
+	}
+	return append(statements, line[start:])
+}
+
+// Truncate shortens the text to at most maxLength characters without splitting any characters.
+func Truncate(text string, maxLength int) string {
+	if maxLength < 0 {
+		return text
+	}
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+	return string(runes[:maxLength])
+}
@@ -0,0 +1,200 @@
+package console
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"hello", "hello"},
+		{`say "hi"`, `say \"hi\"`},
+		{`C:\path`, `C:\\path`},
+		{`\"`, `\\\"`},
+		{"line\nbreak", "line break"},
+		{"tab\tand\x00null", "tab and null"},
+		{"semi;colon", "semi;colon"},
+		{`trailing\`, "trailing"},
+		{`trailing\\ \\`, `trailing\\\\ `},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Escape(tt.text); got != tt.want {
+				t.Errorf("Escape(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", `""`},
+		{"hello world", `"hello world"`},
+		{`"; shutdown; say "`, `"\"; shutdown; say \""`},
+		{`trailing\`, `"trailing"`},
+		{`C:\path\`, `"C:\\path"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Quote(tt.text); got != tt.want {
+				t.Errorf("Quote(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatements(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", []string{""}},
+		{"status", []string{"status"}},
+		{"kick 1; ban 2", []string{"kick 1", " ban 2"}},
+		{"say \"a; b\"", []string{"say \"a; b\""}},
+		{"say \"a; b\"; shutdown", []string{"say \"a; b\"", " shutdown"}},
+		{`say "a\"; b"; shutdown`, []string{`say "a\"; b"`, " shutdown"}},
+		{`say "C:\\path"; shutdown`, []string{`say "C:\\path"`, " shutdown"}},
+		{`say "a\\"; shutdown`, []string{`say "a\\"; shutdown`}},
+		{`say "a\\\"; shutdown`, []string{`say "a\\\"; shutdown`}},
+		{`say "a\\\\"; shutdown`, []string{`say "a\\\\"; shutdown`}},
+		{`say \"; shutdown`, []string{`say \"`, " shutdown"}},
+		{`say a\\"; shutdown`, []string{`say a\\"`, " shutdown"}},
+		{`say \\; shutdown`, []string{`say \\`, " shutdown"}},
+		{`say "x" # comment; shutdown`, []string{`say "x" `}},
+		{`say "#"; shutdown`, []string{`say "#"`, " shutdown"}},
+		{`status; # shutdown`, []string{"status", " "}},
+		{`say x\# comment; shutdown`, []string{`say x\`}},
+		{`say "unterminated; shutdown`, []string{`say "unterminated; shutdown`}},
+		{"a;;b;", []string{"a", "", "b", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := Statements(tt.line); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Statements(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatementsOfQuotedArguments(t *testing.T) {
+	texts := []string{
+		"hello; shutdown",
+		`"; shutdown; say "`,
+		`\"; shutdown`,
+		`\\"; shutdown; "`,
+		`trailing\`,
+		`trailing\\`,
+		`C:\path\; shutdown; say \`,
+		"# comment; shutdown",
+		"line\nbreak; shutdown",
+	}
+	for _, text := range texts {
+		t.Run(text, func(t *testing.T) {
+			line := "say " + Quote(text)
+			if got := Statements(line); len(got) != 1 {
+				t.Errorf("Statements(%q) = %q, want a single statement", line, got)
+			}
+		})
+	}
+}
+
+func TestReplace(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		text string
+		want string
+	}{
+		{"unquoted", "say {REASON}", "a; shutdown", `say "a; shutdown"`},
+		{"quoted", `say "{REASON}"`, "a; shutdown", `say "a; shutdown"`},
+		{"within text", `say "aborted: {REASON}!"`, `a"; shutdown`, `say "aborted: a\"; shutdown!"`},
+		{"after quoted argument", `say "x" {REASON}`, "a", `say "x" "a"`},
+		{"escaped quote", `say \"{REASON}`, "a", `say \""a"`},
+		{"after backslash", `say \{REASON}`, "a; shutdown", `say \ "a; shutdown"`},
+		{"after escaped backslash within quotes", `say "\\{REASON}"`, `"; shutdown`, `say "\\\"; shutdown"`},
+		{"multiple", "{REASON} {REASON}", "a", `"a" "a"`},
+		{"missing", "say hi", "a", "say hi"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Replace(tt.line, "{REASON}", tt.text)
+			if got != tt.want {
+				t.Errorf("Replace(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+			if statements := Statements(got); len(statements) != len(Statements(tt.line)) {
+				t.Errorf("Replace(%q) = %q, contains %d statements", tt.line, got, len(statements))
+			}
+		})
+	}
+}
+
+func TestReplaceAllFunc(t *testing.T) {
+	param := regexp.MustCompile(`\{[0-9]+\}`)
+	args := map[string]string{"{1}": "a; b", "{2}": "{1}"}
+
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"sv_map {1}", `sv_map "a; b"`},
+		{`say "{1} and {2}"`, `say "a; b and {1}"`},
+		{`say {2} "{1}"`, `say "{1}" "a; b"`},
+		{"{3}", `"<nil>"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got := ReplaceAllFunc(tt.line, param, func(match string, inQuotes bool) string {
+				value, found := args[match]
+				if !found {
+					value = "<nil>"
+				}
+				if inQuotes {
+					return Escape(value)
+				}
+				return Quote(value)
+			})
+			if got != tt.want {
+				t.Errorf("ReplaceAllFunc(%q) = %q, want %q", tt.line, got, tt.want)
+			}
+			if len(Statements(got)) != 1 {
+				t.Errorf("ReplaceAllFunc(%q) = %q, contains several statements", tt.line, got)
+			}
+		})
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"vpn", "vpn"},
+		{"  known   vpn provider ", "known vpn provider"},
+		{"vpn; shutdown", "vpn shutdown"},
+		{"vpn\"; shutdown; say \"", "vpn shutdown say"},
+		{"vpn\nshutdown", "vpn shutdown"},
+		{"vpn\r\n\tproxy\x00", "vpn proxy"},
+		{"vpn \\ proxy", "vpn \\ proxy"},
+		{"vpn proxy\\", "vpn proxy"},
+		{"vpn \\\\", "vpn"},
+		{"vpn # shutdown", "vpn shutdown"},
+		{"ümläut vpn", "ümläut vpn"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Sanitize(tt.text); got != tt.want {
+				t.Errorf("Sanitize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
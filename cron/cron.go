@@ -0,0 +1,138 @@
+// Package cron parses classic five field cron expressions.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	shortcuts = map[string]string{
+		"@hourly":   "0 * * * *",
+		"@daily":    "0 0 * * *",
+		"@midnight": "0 0 * * *",
+		"@weekly":   "0 0 * * 0",
+		"@monthly":  "0 0 1 * *",
+		"@yearly":   "0 0 1 1 *",
+		"@annually": "0 0 1 1 *",
+	}
+)
+
+// Schedule is a parsed cron expression with the fields
+// minute hour day-of-month month day-of-week
+// every field is a bit set of the allowed values.
+type Schedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+	// restricted day fields are combined with OR like in the classic cron implementation
+	dayOfMonthStar bool
+	dayOfWeekStar  bool
+}
+
+// Parse parses a classic five field cron expression like "*/15 8-20 * * 1-5"
+// or one of the shortcuts @hourly, @daily, @midnight, @weekly, @monthly, @yearly and @annually
+func Parse(expr string) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if shortcut, found := shortcuts[strings.ToLower(expr)]; found {
+		expr = shortcut
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("invalid cron expression %q: expected 5 fields", expr)
+	}
+
+	var (
+		cs  Schedule
+		err error
+	)
+	if cs.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return Schedule{}, fmt.Errorf("invalid minute field: %w", err)
+	}
+	if cs.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return Schedule{}, fmt.Errorf("invalid hour field: %w", err)
+	}
+	if cs.dayOfMonth, err = parseField(fields[2], 1, 31); err != nil {
+		return Schedule{}, fmt.Errorf("invalid day of month field: %w", err)
+	}
+	if cs.month, err = parseField(fields[3], 1, 12); err != nil {
+		return Schedule{}, fmt.Errorf("invalid month field: %w", err)
+	}
+	// 0 and 7 are both sunday
+	if cs.dayOfWeek, err = parseField(fields[4], 0, 7); err != nil {
+		return Schedule{}, fmt.Errorf("invalid day of week field: %w", err)
+	}
+	if cs.dayOfWeek&(1<<7) != 0 {
+		cs.dayOfWeek |= 1
+	}
+	cs.dayOfMonthStar = strings.HasPrefix(fields[2], "*")
+	cs.dayOfWeekStar = strings.HasPrefix(fields[4], "*")
+	return cs, nil
+}
+
+// parseField parses comma separated lists of *, n, a-b with an optional /step
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			value, err := strconv.Atoi(part[idx+1:])
+			if err != nil || value <= 0 {
+				return 0, fmt.Errorf("invalid step: %s", part)
+			}
+			rangePart, step = part[:idx], value
+		}
+
+		low, high := min, max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err1, err2 error
+			low, err1 = strconv.Atoi(bounds[0])
+			high, err2 = strconv.Atoi(bounds[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range: %s", part)
+			}
+		default:
+			value, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value: %s", part)
+			}
+			low, high = value, value
+			if step > 1 {
+				// n/step means from n to max
+				high = max
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%s is out of range [%d:%d]", part, min, max)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+// Matches returns true if the schedule is due at the minute of t.
+func (cs *Schedule) Matches(t time.Time) bool {
+	if cs.minute&(1<<uint(t.Minute())) == 0 ||
+		cs.hour&(1<<uint(t.Hour())) == 0 ||
+		cs.month&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+
+	domMatch := cs.dayOfMonth&(1<<uint(t.Day())) != 0
+	dowMatch := cs.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if cs.dayOfMonthStar || cs.dayOfWeekStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,82 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantErr bool
+	}{
+		{"* * * * *", false},
+		{"*/15 8-20 * * 1-5", false},
+		{"0,30 * 1 1,6 0", false},
+		{"0 0 * * 7", false},
+		{"5/10 * * * *", false},
+		{"@hourly", false},
+		{"@Daily", false},
+		{"  @weekly  ", false},
+		{"", true},
+		{"* * * *", true},
+		{"* * * * * *", true},
+		{"60 * * * *", true},
+		{"* 24 * * *", true},
+		{"* * 0 * *", true},
+		{"* * * 13 *", true},
+		{"* * * * 8", true},
+		{"*/0 * * * *", true},
+		{"10-5 * * * *", true},
+		{"a * * * *", true},
+		{"@every", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScheduleMatches(t *testing.T) {
+	// 2021-03-01 is a monday
+	monday := time.Date(2021, time.March, 1, 8, 15, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		expr string
+		at   time.Time
+		want bool
+	}{
+		{"every minute", "* * * * *", monday, true},
+		{"step", "*/15 * * * *", monday, true},
+		{"step mismatch", "*/15 * * * *", monday.Add(time.Minute), false},
+		{"offset step", "5/10 * * * *", monday, true},
+		{"hour range", "15 8-20 * * *", monday, true},
+		{"hour range mismatch", "15 9-20 * * *", monday, false},
+		{"weekday", "15 8 * * 1-5", monday, true},
+		{"weekend", "15 8 * * 0,6", monday, false},
+		{"sunday as 7", "* * * * 7", monday.AddDate(0, 0, 6), true},
+		{"day of month", "15 8 1 * *", monday, true},
+		{"month", "15 8 * 4 *", monday, false},
+		// restricted day of month and day of week match if either of them matches
+		{"day of month or weekday", "15 8 15 * 1", monday, true},
+		{"neither day", "15 8 15 * 2", monday, false},
+		{"hourly", "@hourly", monday.Truncate(time.Hour), true},
+		{"hourly mismatch", "@hourly", monday, false},
+		{"yearly", "@yearly", time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse(%q) unexpected error: %v", tt.expr, err)
+			}
+			if got := schedule.Matches(tt.at); got != tt.want {
+				t.Errorf("Matches(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package ipranges
+
+import (
+	"bytes"
+	"container/heap"
+	"sort"
+)
+
+// Entry is a range with its reason.
+type Entry struct {
+	Range
+	Reason string
+}
+
+// Insert adds the entry to the entries that are sorted by their lower boundary and disjoint.
+// Overlapping parts of previous entries are replaced. The resulting entries are returned
+// with the entries that were removed and the entries that were added, which includes
+// the inserted entry itself.
+func Insert(entries []Entry, entry Entry) (result, removed, added []Entry) {
+	return splice(entries, entry.Range, &entry)
+}
+
+// Cut removes the range from the entries that are sorted by their lower boundary and disjoint.
+// The resulting entries are returned with the entries that were removed and the remaining
+// parts of them that were added.
+func Cut(entries []Entry, r Range) (result, removed, added []Entry) {
+	return splice(entries, r, nil)
+}
+
+func splice(entries []Entry, r Range, inserted *Entry) (result, removed, added []Entry) {
+	// entries are disjoint, so the upper boundaries are sorted as well
+	first := sort.Search(len(entries), func(i int) bool {
+		return bytes.Compare(entries[i].High, r.Low) >= 0
+	})
+	last := first
+	for last < len(entries) && bytes.Compare(entries[last].Low, r.High) <= 0 {
+		last++
+	}
+	removed = append(removed, entries[first:last]...)
+
+	result = make([]Entry, 0, len(entries)-len(removed)+3)
+	result = append(result, entries[:first]...)
+	if len(removed) > 0 && bytes.Compare(removed[0].Low, r.Low) < 0 {
+		left := removed[0]
+		left.High = previous(r.Low)
+		result = append(result, left)
+		added = append(added, left)
+	}
+	if inserted != nil {
+		result = append(result, *inserted)
+		added = append(added, *inserted)
+	}
+	if len(removed) > 0 && bytes.Compare(removed[len(removed)-1].High, r.High) > 0 {
+		right := removed[len(removed)-1]
+		right.Low = next(r.High)
+		result = append(result, right)
+		added = append(added, right)
+	}
+	result = append(result, entries[last:]...)
+	return result, removed, added
+}
+
+// Overlay inserts all entries in the passed order at once and returns the resulting
+// disjoint entries sorted by their lower boundary, later entries replace the overlapping
+// parts of previous entries. Unlike inserting the entries one by one, the entries are
+// sorted only once.
+func Overlay(entries []Entry) []Entry {
+	type boundary struct {
+		ip    []byte
+		idx   int
+		start bool
+	}
+	boundaries := make([]boundary, 0, 2*len(entries))
+	for idx, entry := range entries {
+		boundaries = append(boundaries, boundary{entry.Low.To16(), idx, true})
+		if !entry.High.Equal(maxIP) {
+			boundaries = append(boundaries, boundary{next(entry.High), idx, false})
+		}
+	}
+	sort.Slice(boundaries, func(i, j int) bool {
+		return bytes.Compare(boundaries[i].ip, boundaries[j].ip) < 0
+	})
+
+	result := make([]Entry, 0, len(entries))
+	// index of the entry that every element of the result belongs to
+	origins := make([]int, 0, len(entries))
+	active := &indexHeap{}
+	ended := make([]bool, len(entries))
+	for i := 0; i < len(boundaries); {
+		low := boundaries[i].ip
+		for ; i < len(boundaries) && bytes.Equal(boundaries[i].ip, low); i++ {
+			if boundaries[i].start {
+				heap.Push(active, boundaries[i].idx)
+			} else {
+				ended[boundaries[i].idx] = true
+			}
+		}
+		for active.Len() > 0 && ended[(*active)[0]] {
+			heap.Pop(active)
+		}
+		if active.Len() == 0 {
+			continue
+		}
+
+		// the latest entry that contains the IPs up to the next boundary wins
+		winner := (*active)[0]
+		high := maxIP
+		if i < len(boundaries) {
+			high = previous(boundaries[i].ip)
+		}
+		last := len(result) - 1
+		if last >= 0 && origins[last] == winner && bytes.Equal(next(result[last].High), low) {
+			result[last].High = high
+			continue
+		}
+		result = append(result, Entry{Range{low, high}, entries[winner].Reason})
+		origins = append(origins, winner)
+	}
+	return result
+}
+
+// indexHeap is a max heap of entry indices
+type indexHeap []int
+
+func (h indexHeap) Len() int            { return len(h) }
+func (h indexHeap) Less(i, j int) bool  { return h[i] > h[j] }
+func (h indexHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *indexHeap) Push(x interface{}) { *h = append(*h, x.(int)) }
+func (h *indexHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
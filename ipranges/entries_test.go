@@ -0,0 +1,132 @@
+package ipranges
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func mustEntries(t *testing.T, ranges ...string) []Entry {
+	t.Helper()
+	entries := make([]Entry, 0, len(ranges))
+	for _, value := range ranges {
+		parts := strings.SplitN(value, "#", 2)
+		r, err := Parse(parts[0])
+		if err != nil {
+			t.Fatalf("invalid test range %s: %v", value, err)
+		}
+		entries = append(entries, Entry{Range: r, Reason: strings.TrimSpace(parts[1])})
+	}
+	return entries
+}
+
+func fmtEntries(entries []Entry) []string {
+	result := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, fmt.Sprintf("%s # %s", entry.Range, entry.Reason))
+	}
+	return result
+}
+
+func TestInsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []string
+		inserted string
+		want     []string
+		removed  int
+	}{
+		{"empty", nil, "2001:db8::/64 # a", []string{"2001:db8:: - 2001:db8::ffff:ffff:ffff:ffff # a"}, 0},
+		{"disjoint", []string{"2001:db8::10 - 2001:db8::1f # a"}, "2001:db8::1 # b", []string{"2001:db8::1 # b", "2001:db8::10 - 2001:db8::1f # a"}, 0},
+		{"inside", []string{"2001:db8::10 - 2001:db8::1f # a"}, "2001:db8::15 # b", []string{"2001:db8::10 - 2001:db8::14 # a", "2001:db8::15 # b", "2001:db8::16 - 2001:db8::1f # a"}, 1},
+		{"overlap left", []string{"2001:db8::10 - 2001:db8::1f # a"}, "2001:db8::1 - 2001:db8::12 # b", []string{"2001:db8::1 - 2001:db8::12 # b", "2001:db8::13 - 2001:db8::1f # a"}, 1},
+		{"overlap right", []string{"2001:db8::10 - 2001:db8::1f # a"}, "2001:db8::1e - 2001:db8::30 # b", []string{"2001:db8::10 - 2001:db8::1d # a", "2001:db8::1e - 2001:db8::30 # b"}, 1},
+		{"covers several", []string{"2001:db8::1 # a", "2001:db8::5 - 2001:db8::6 # b", "2001:db8::9 # c"}, "2001:db8:: - 2001:db8::8 # d", []string{"2001:db8:: - 2001:db8::8 # d", "2001:db8::9 # c"}, 2},
+		{"replace", []string{"2001:db8::1 # a"}, "2001:db8::1 # b", []string{"2001:db8::1 # b"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inserted := mustEntries(t, tt.inserted)[0]
+			result, removed, _ := Insert(mustEntries(t, tt.entries...), inserted)
+			if got := fmtEntries(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Insert() = %v, want %v", got, tt.want)
+			}
+			if len(removed) != tt.removed {
+				t.Errorf("Insert() removed %d entries, want %d", len(removed), tt.removed)
+			}
+		})
+	}
+}
+
+func TestCut(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		cut     string
+		want    []string
+		added   int
+	}{
+		{"empty", nil, "2001:db8::/64", []string{}, 0},
+		{"no overlap", []string{"2001:db8::1 # a"}, "2001:db8::2", []string{"2001:db8::1 # a"}, 0},
+		{"split", []string{"2001:db8::10 - 2001:db8::1f # a"}, "2001:db8::15", []string{"2001:db8::10 - 2001:db8::14 # a", "2001:db8::16 - 2001:db8::1f # a"}, 2},
+		{"whole", []string{"2001:db8::10 - 2001:db8::1f # a"}, "2001:db8::/64", []string{}, 0},
+		{"across", []string{"2001:db8::1 - 2001:db8::5 # a", "2001:db8::8 - 2001:db8::f # b"}, "2001:db8::3 - 2001:db8::9", []string{"2001:db8::1 - 2001:db8::2 # a", "2001:db8::a - 2001:db8::f # b"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Parse(tt.cut)
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, _, added := Cut(mustEntries(t, tt.entries...), r)
+			if got := fmtEntries(result); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Cut() = %v, want %v", got, tt.want)
+			}
+			if len(added) != tt.added {
+				t.Errorf("Cut() added %d entries, want %d", len(added), tt.added)
+			}
+		})
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []string
+	}{
+		{"empty", nil, []string{}},
+		{"single", []string{"10.0.0.0/24 # a"}, []string{"10.0.0.0 - 10.0.0.255 # a"}},
+		{"later wins", []string{"10.0.0.0/24 # a", "10.0.0.10 - 10.0.0.19 # b"}, []string{"10.0.0.0 - 10.0.0.9 # a", "10.0.0.10 - 10.0.0.19 # b", "10.0.0.20 - 10.0.0.255 # a"}},
+		{"earlier loses", []string{"10.0.0.10 - 10.0.0.19 # b", "10.0.0.0/24 # a"}, []string{"10.0.0.0 - 10.0.0.255 # a"}},
+		{"adjacent", []string{"10.0.0.0 - 10.0.0.9 # a", "10.0.0.10 - 10.0.0.19 # a"}, []string{"10.0.0.0 - 10.0.0.9 # a", "10.0.0.10 - 10.0.0.19 # a"}},
+		{"nested", []string{"2001:db8::/32 # a", "2001:db8::/48 # b", "2001:db8::1 # c"}, []string{"2001:db8:: # b", "2001:db8::1 # c", "2001:db8::2 - 2001:db8:0:ffff:ffff:ffff:ffff:ffff # b", "2001:db8:1:: - 2001:db8:ffff:ffff:ffff:ffff:ffff:ffff # a"}},
+		{"highest ip", []string{"ffff::/16 # a", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe # b"}, []string{"ffff:: - ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffd # a", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe # b", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff # a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fmtEntries(Overlay(mustEntries(t, tt.entries...)))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Overlay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverlayMatchesInsert(t *testing.T) {
+	entries := mustEntries(t,
+		"2001:db8::/120 # a",
+		"2001:db8::10 - 2001:db8::80 # b",
+		"2001:db8::20 # c",
+		"2001:db8::7f - 2001:db8::200 # d",
+		"2001:db8::/124 # e",
+	)
+	inserted := make([]Entry, 0)
+	for _, entry := range entries {
+		inserted, _, _ = Insert(inserted, entry)
+	}
+	if got, want := fmtEntries(Overlay(entries)), fmtEntries(inserted); !reflect.DeepEqual(got, want) {
+		t.Errorf("Overlay() = %v, want %v", got, want)
+	}
+}
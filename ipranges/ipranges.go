@@ -0,0 +1,116 @@
+// Package ipranges contains helpers that parse, cut and combine IPv4 and IPv6 ranges.
+// Both address families are represented by their 16 byte form, IPv4 addresses are
+// stored as IPv4 mapped IPv6 addresses.
+package ipranges
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/jxsl13/goripr"
+)
+
+// maxIP is the highest 16 byte IP
+var maxIP = net.IP(bytes.Repeat([]byte{0xff}, net.IPv6len))
+
+// Range is the closed interval [Low, High] of IPs.
+type Range struct {
+	Low  net.IP
+	High net.IP
+}
+
+// Parse parses an IPv4 or IPv6 address, CIDR or range <IP> - <IP>.
+// Both boundaries of a range must belong to the same address family,
+// IPv4 mapped IPv6 addresses are considered to be IPv4.
+func Parse(ipRange string) (Range, error) {
+	ipRange = strings.TrimSpace(ipRange)
+	invalid := fmt.Errorf("%w: %s", goripr.ErrInvalidRange, ipRange)
+
+	switch {
+	case strings.Contains(ipRange, "/"):
+		ip, ipNet, err := net.ParseCIDR(ipRange)
+		if err != nil {
+			return Range{}, invalid
+		}
+		mask := ipNet.Mask
+		if ip.To4() != nil && len(mask) == net.IPv4len {
+			// expand the mask to the 16 byte form
+			mask = append(net.CIDRMask(96, 128)[:12], mask...)
+		}
+		low := ipNet.IP.To16()
+		high := make(net.IP, net.IPv6len)
+		for idx := range low {
+			high[idx] = low[idx] | ^mask[idx]
+		}
+		return Range{low, high}, nil
+	case strings.Contains(ipRange, "-"):
+		bounds := strings.SplitN(ipRange, "-", 2)
+		low := net.ParseIP(strings.TrimSpace(bounds[0]))
+		high := net.ParseIP(strings.TrimSpace(bounds[1]))
+		if low == nil || high == nil || isIPv4(low) != isIPv4(high) || bytes.Compare(low.To16(), high.To16()) > 0 {
+			return Range{}, invalid
+		}
+		return Range{low.To16(), high.To16()}, nil
+	default:
+		ip := net.ParseIP(ipRange)
+		if ip == nil {
+			return Range{}, invalid
+		}
+		return Range{ip.To16(), ip.To16()}, nil
+	}
+}
+
+// IPv6 returns true if the range contains IPv6 addresses.
+func (r Range) IPv6() bool {
+	return !isIPv4(r.Low)
+}
+
+// Contains returns true if the ip is within the range.
+func (r Range) Contains(ip net.IP) bool {
+	ip = ip.To16()
+	return ip != nil && bytes.Compare(r.Low, ip) <= 0 && bytes.Compare(ip, r.High) <= 0
+}
+
+// String returns the single IP or the range <IP> - <IP>.
+func (r Range) String() string {
+	if r.Low.Equal(r.High) {
+		return r.Low.String()
+	}
+	return fmt.Sprintf("%s - %s", r.Low, r.High)
+}
+
+func (r Range) overlaps(other Range) bool {
+	return bytes.Compare(r.Low, other.High) <= 0 && bytes.Compare(other.Low, r.High) <= 0
+}
+
+func isIPv4(ip net.IP) bool {
+	return ip.To4() != nil
+}
+
+// next returns ip+1, the caller must prevent overflows
+func next(ip net.IP) net.IP {
+	result := make(net.IP, net.IPv6len)
+	copy(result, ip.To16())
+	for idx := len(result) - 1; idx >= 0; idx-- {
+		result[idx]++
+		if result[idx] != 0 {
+			break
+		}
+	}
+	return result
+}
+
+// previous returns ip-1, the caller must prevent underflows
+func previous(ip net.IP) net.IP {
+	result := make(net.IP, net.IPv6len)
+	copy(result, ip.To16())
+	for idx := len(result) - 1; idx >= 0; idx-- {
+		result[idx]--
+		if result[idx] != 0xff {
+			break
+		}
+	}
+	return result
+}
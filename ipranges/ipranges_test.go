@@ -0,0 +1,53 @@
+package ipranges
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jxsl13/goripr"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		ipRange string
+		want    string
+		ipv6    bool
+		wantErr bool
+	}{
+		{"1.2.3.4", "1.2.3.4", false, false},
+		{" 1.2.3.4 ", "1.2.3.4", false, false},
+		{"10.0.0.0/8", "10.0.0.0 - 10.255.255.255", false, false},
+		{"10.0.0.1/8", "10.0.0.0 - 10.255.255.255", false, false},
+		{"1.2.3.4 - 1.2.3.10", "1.2.3.4 - 1.2.3.10", false, false},
+		{"1.2.3.4-1.2.3.4", "1.2.3.4", false, false},
+		{"::ffff:1.2.3.4", "1.2.3.4", false, false},
+		{"2001:db8::1", "2001:db8::1", true, false},
+		{"2001:db8::/32", "2001:db8:: - 2001:db8:ffff:ffff:ffff:ffff:ffff:ffff", true, false},
+		{"2001:db8::1 - 2001:db8::ff", "2001:db8::1 - 2001:db8::ff", true, false},
+		{"1.2.3.10 - 1.2.3.4", "", false, true},
+		{"1.2.3.4 - 2001:db8::1", "", false, true},
+		{"1.2.3.4/33", "", false, true},
+		{"1.2.3", "", false, true},
+		{"", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ipRange, func(t *testing.T) {
+			got, err := Parse(tt.ipRange)
+			if tt.wantErr {
+				if !errors.Is(err, goripr.ErrInvalidRange) {
+					t.Errorf("Parse() error = %v, want %v", err, goripr.ErrInvalidRange)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Parse() = %s, want %s", got, tt.want)
+			}
+			if got.IPv6() != tt.ipv6 {
+				t.Errorf("Parse().IPv6() = %v, want %v", got.IPv6(), tt.ipv6)
+			}
+		})
+	}
+}
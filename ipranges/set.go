@@ -0,0 +1,122 @@
+package ipranges
+
+import (
+	"bytes"
+	"net"
+	"sort"
+)
+
+// Set is the union of ranges, which is stored as sorted disjoint ranges per address family.
+type Set struct {
+	v4 []Range
+	v6 []Range
+}
+
+// NewSet merges the overlapping and adjacent ranges.
+func NewSet(ranges []Range) Set {
+	var s Set
+	for _, r := range ranges {
+		if r.IPv6() {
+			s.v6 = append(s.v6, r)
+		} else {
+			s.v4 = append(s.v4, r)
+		}
+	}
+	s.v4 = merge(s.v4)
+	s.v6 = merge(s.v6)
+	return s
+}
+
+func merge(ranges []Range) []Range {
+	if len(ranges) == 0 {
+		return nil
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return bytes.Compare(ranges[i].Low, ranges[j].Low) < 0
+	})
+	result := []Range{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &result[len(result)-1]
+		if last.High.Equal(maxIP) || bytes.Compare(r.Low, next(last.High)) <= 0 {
+			if bytes.Compare(r.High, last.High) > 0 {
+				last.High = r.High
+			}
+			continue
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// Len returns the number of disjoint ranges.
+func (s Set) Len() int {
+	return len(s.v4) + len(s.v6)
+}
+
+// Ranges returns the disjoint IPv4 ranges followed by the disjoint IPv6 ranges.
+func (s Set) Ranges() []Range {
+	result := make([]Range, 0, s.Len())
+	result = append(result, s.v4...)
+	return append(result, s.v6...)
+}
+
+// Contains returns true if one of the ranges contains the ip.
+func (s Set) Contains(ip net.IP) bool {
+	ip = ip.To16()
+	if ip == nil {
+		return false
+	}
+	ranges := s.v6
+	if isIPv4(ip) {
+		ranges = s.v4
+	}
+	idx := sort.Search(len(ranges), func(i int) bool {
+		return bytes.Compare(ranges[i].High, ip) >= 0
+	})
+	return idx < len(ranges) && ranges[idx].Contains(ip)
+}
+
+// Clip returns the parts of the range that are contained in the set.
+func (s Set) Clip(r Range) []Range {
+	ranges := s.v4
+	if r.IPv6() {
+		ranges = s.v6
+	}
+
+	first := sort.Search(len(ranges), func(i int) bool {
+		return bytes.Compare(ranges[i].High, r.Low) >= 0
+	})
+	result := make([]Range, 0)
+	for _, candidate := range ranges[first:] {
+		if !candidate.overlaps(r) {
+			break
+		}
+		part := r
+		if bytes.Compare(candidate.Low, part.Low) > 0 {
+			part.Low = candidate.Low
+		}
+		if bytes.Compare(candidate.High, part.High) < 0 {
+			part.High = candidate.High
+		}
+		result = append(result, part)
+	}
+	return result
+}
+
+// Diff returns the ranges that were added or whose reason changed and the ranges that were removed.
+// Both maps contain the ranges with their reasons.
+func Diff(previous, current map[string]string) (added, removed map[string]string) {
+	added = make(map[string]string)
+	removed = make(map[string]string)
+	for ipRange, reason := range current {
+		if previousReason, found := previous[ipRange]; !found || previousReason != reason {
+			added[ipRange] = reason
+		}
+	}
+	for ipRange, reason := range previous {
+		if _, found := current[ipRange]; !found {
+			removed[ipRange] = reason
+		}
+	}
+	return added, removed
+}
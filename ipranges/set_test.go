@@ -0,0 +1,159 @@
+package ipranges
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func mustRanges(t *testing.T, ranges ...string) []Range {
+	t.Helper()
+	result := make([]Range, 0, len(ranges))
+	for _, value := range ranges {
+		r, err := Parse(value)
+		if err != nil {
+			t.Fatalf("invalid test range %s: %v", value, err)
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+func fmtRanges(ranges []Range) []string {
+	result := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		result = append(result, r.String())
+	}
+	return result
+}
+
+func TestNewSet(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []string
+		want   []string
+	}{
+		{"empty", nil, []string{}},
+		{"disjoint", []string{"10.0.0.5", "10.0.0.1"}, []string{"10.0.0.1", "10.0.0.5"}},
+		{"overlapping", []string{"10.0.0.0/24", "10.0.0.128 - 10.0.1.5"}, []string{"10.0.0.0 - 10.0.1.5"}},
+		{"adjacent", []string{"10.0.0.0 - 10.0.0.9", "10.0.0.10 - 10.0.0.19"}, []string{"10.0.0.0 - 10.0.0.19"}},
+		{"contained", []string{"10.0.0.0/8", "10.1.0.0/16"}, []string{"10.0.0.0 - 10.255.255.255"}},
+		{"families", []string{"2001:db8::/32", "10.0.0.1", "::/0"}, []string{"10.0.0.1", ":: - ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fmtRanges(NewSet(mustRanges(t, tt.ranges...)).Ranges())
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewSet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetContains(t *testing.T) {
+	set := NewSet(mustRanges(t, "10.0.0.10 - 10.0.0.19", "1.2.3.4", "2001:db8::/64"))
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.0.0.9", false},
+		{"10.0.0.10", true},
+		{"10.0.0.19", true},
+		{"10.0.0.20", false},
+		{"1.2.3.4", true},
+		{"::ffff:1.2.3.4", true},
+		{"2001:db8::1", true},
+		{"2001:db8:0:1::", false},
+		{"invalid", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ip, func(t *testing.T) {
+			if got := set.Contains(net.ParseIP(tt.ip)); got != tt.want {
+				t.Errorf("Contains(%s) = %v, want %v", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetClip(t *testing.T) {
+	set := NewSet(mustRanges(t, "10.0.0.10 - 10.0.0.19", "10.0.0.30 - 10.0.0.39", "2001:db8::/64"))
+	tests := []struct {
+		ipRange string
+		want    []string
+	}{
+		{"10.0.0.1", []string{}},
+		{"10.0.0.15", []string{"10.0.0.15"}},
+		{"10.0.0.0/24", []string{"10.0.0.10 - 10.0.0.19", "10.0.0.30 - 10.0.0.39"}},
+		{"10.0.0.15 - 10.0.0.35", []string{"10.0.0.15 - 10.0.0.19", "10.0.0.30 - 10.0.0.35"}},
+		{"10.0.0.20 - 10.0.0.29", []string{}},
+		{"2001:db8::/32", []string{"2001:db8:: - 2001:db8::ffff:ffff:ffff:ffff"}},
+		{"::ffff:10.0.0.12", []string{"10.0.0.12"}},
+		// IPv6 ranges do not overlap with IPv4 regions
+		{"::/0", []string{"2001:db8:: - 2001:db8::ffff:ffff:ffff:ffff"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ipRange, func(t *testing.T) {
+			got := fmtRanges(set.Clip(mustRanges(t, tt.ipRange)[0]))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Clip() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name        string
+		previous    map[string]string
+		current     map[string]string
+		wantAdded   map[string]string
+		wantRemoved map[string]string
+	}{
+		{
+			name:        "first import",
+			previous:    nil,
+			current:     map[string]string{"10.0.0.0/8": "vpn"},
+			wantAdded:   map[string]string{"10.0.0.0/8": "vpn"},
+			wantRemoved: map[string]string{},
+		},
+		{
+			name:        "unchanged",
+			previous:    map[string]string{"10.0.0.0/8": "vpn"},
+			current:     map[string]string{"10.0.0.0/8": "vpn"},
+			wantAdded:   map[string]string{},
+			wantRemoved: map[string]string{},
+		},
+		{
+			name:        "reason changed",
+			previous:    map[string]string{"10.0.0.0/8": "vpn"},
+			current:     map[string]string{"10.0.0.0/8": "proxy"},
+			wantAdded:   map[string]string{"10.0.0.0/8": "proxy"},
+			wantRemoved: map[string]string{},
+		},
+		{
+			name:        "added and removed",
+			previous:    map[string]string{"10.0.0.0/8": "vpn", "2001:db8::/32": "vpn"},
+			current:     map[string]string{"10.0.0.0/8": "vpn", "1.2.3.4": "proxy"},
+			wantAdded:   map[string]string{"1.2.3.4": "proxy"},
+			wantRemoved: map[string]string{"2001:db8::/32": "vpn"},
+		},
+		{
+			name:        "file removed",
+			previous:    map[string]string{"10.0.0.0/8": "vpn"},
+			current:     nil,
+			wantAdded:   map[string]string{},
+			wantRemoved: map[string]string{"10.0.0.0/8": "vpn"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := Diff(tt.previous, tt.current)
+			if !reflect.DeepEqual(added, tt.wantAdded) {
+				t.Errorf("Diff() added = %v, want %v", added, tt.wantAdded)
+			}
+			if !reflect.DeepEqual(removed, tt.wantRemoved) {
+				t.Errorf("Diff() removed = %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/service"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// Macro manages named sequences of econ commands.
+type Macro struct {
+	Ctx *bot.Context
+}
+
+func (m *Macro) Setup(sub *bot.Subcommand) {
+	sub.Description = "manage named sequences of econ commands"
+}
+
+// Define creates or replaces a macro, every following line is either an econ command
+// that may contain the parameters {1}, {2}, ... or a sleep <duration> step:
+// !macro define <name>
+// sv_password {1}
+// sleep 5s
+// restart
+func (m *Macro) Define(msg *gateway.MessageCreateEvent, args bot.RawArguments) (string, error) {
+	if err := config.Modules().ErrIfDiscordLoggingDisabled(); err != nil {
+		return "", err
+	}
+	if err := errIfNotModerator(msg); err != nil {
+		return "", err
+	}
+	name, definition := splitFirst(string(args))
+	if name == "" || definition == "" {
+		return "", errors.New("usage: !macro define <name>, followed by one command or sleep <duration> per line")
+	}
+
+	lines := strings.Split(definition, "\n")
+	for _, line := range lines {
+		if err := config.Discord().CheckCommandPolicy(line); err != nil {
+			return "", err
+		}
+	}
+
+	macro, err := config.Macros().DefineMacro(name, lines, service.Requestor(*msg))
+	if err != nil {
+		return "", fmt.Errorf("failed to define macro %s: %s", name, err)
+	}
+	return fmt.Sprintf("defined macro %s with %d arguments:\n%s", name, macro.Params(), fmtCodeBlock(macro.Lines)), nil
+}
+
+// Run executes a macro on the channel's linked server or on the target that is prefixed with @:
+// !macro run <name> [@target] [arguments...]
+func (m *Macro) Run(msg *gateway.MessageCreateEvent, args bot.ArgumentParts) error {
+	if err := config.Modules().ErrIfDiscordLoggingDisabled(); err != nil {
+		return err
+	}
+	if err := errIfNotModerator(msg); err != nil {
+		return err
+	}
+	if args.Length() == 0 {
+		return errors.New("usage: !macro run <name> [@target] [arguments...]")
+	}
+
+	name := args.Arg(0)
+	target := ""
+	macroArgs := []string(args[1:])
+	if len(macroArgs) > 0 && strings.HasPrefix(macroArgs[0], "@") {
+		target = strings.TrimPrefix(macroArgs[0], "@")
+		macroArgs = macroArgs[1:]
+	}
+
+	steps, err := config.Macros().Expand(name, macroArgs)
+	if err != nil {
+		return err
+	}
+	service.RunMacro(*msg, target, name, steps)
+	return nil
+}
+
+// List lists all macros.
+func (m *Macro) List(msg *gateway.MessageCreateEvent) (string, error) {
+	if err := config.Modules().ErrIfDiscordLoggingDisabled(); err != nil {
+		return "", err
+	}
+	macros := config.Macros().Macros()
+	if len(macros) == 0 {
+		return "no macros defined", nil
+	}
+	lines := make([]string, 0, len(macros))
+	for _, macro := range macros {
+		lines = append(lines, fmt.Sprintf("%s (%d arguments, by %s):\n%s", macro.Name, macro.Params(), macro.Creator, fmtCodeBlock(macro.Lines)))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Delete removes a macro.
+func (m *Macro) Delete(msg *gateway.MessageCreateEvent, name string) (string, error) {
+	if err := config.Modules().ErrIfDiscordLoggingDisabled(); err != nil {
+		return "", err
+	}
+	if err := errIfNotModerator(msg); err != nil {
+		return "", err
+	}
+	if err := config.Macros().RemoveMacro(name); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("deleted macro %s", name), nil
+}
+
+func fmtCodeBlock(lines []string) string {
+	return markdown.WrapInCodeBlock(strings.Join(lines, "\n"))
+}
@@ -5,18 +5,31 @@ import (
 
 	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
 	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/dclog"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/history"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/roster"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/voteguard"
 	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/vpn"
 	"github.com/Teeworlds-Server-Moderation/discord-moderation/service"
 	"github.com/diamondburned/arikawa/v2/bot"
 )
 
 func main() {
+	config.Init()
 	defer config.Close()
 	defer service.Close()
 
 	bot.Run(config.Discord().Token, &Bot{},
 		func(ctx *bot.Context) error {
 			ctx.HasPrefix = bot.NewPrefix("!")
+			ctx.MustRegisterSubcommand(&Alias{})
+			ctx.MustRegisterSubcommand(&Group{})
+			ctx.MustRegisterSubcommand(&Schedule{})
+			ctx.MustRegisterSubcommand(&Macro{})
+			ctx.MustRegisterSubcommand(&VPN{})
+
+			// keep track of connected players
+			service.AddEventProcessor(roster.Track)
+
 			// log to discord
 
 			if config.Modules().ErrIfDiscordLoggingDisabled() == nil {
@@ -24,9 +37,21 @@ func main() {
 				service.AddEventProcessor(dclog.DiscordLog)
 			}
 
+			if config.Modules().ErrIfPlayerHistoryDisabled() == nil {
+				log.Println("enabled player history module")
+				service.AddEventProcessor(history.Store)
+			}
+
+			if config.Modules().ErrIfVoteGuardDisabled() == nil {
+				log.Println("enabled vote guard module")
+				service.AddEventProcessor(voteguard.Guard)
+			}
+
 			if config.Modules().ErrIfVPNDetectionDisabled() == nil {
 				log.Println("enabled vpn detection module")
 				service.AddEventProcessor(vpn.Detect)
+				go vpn.Watch(ctx)
+				go vpn.FetchPeriodically(ctx)
 			}
 
 			return service.Start(ctx)
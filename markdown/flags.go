@@ -265,6 +265,11 @@ var (
 	}
 )
 
+// CountryCode returns the ISO 3166-1 alpha-2 code of a given flag value, empty if unknown
+func CountryCode(value int) string {
+	return flags[value]
+}
+
 // Flag returns a string representation of a given flag value
 func Flag(value int) string {
 	if value <= 0 {
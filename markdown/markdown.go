@@ -41,6 +41,12 @@ func WrapInInlineCodeBlock(text string) string {
 	return WrapInCustom(text, "`")
 }
 
+// WrapInCodeBlock puts the multi line text into a code block, code block delimiters
+// inside of the text are broken up with a zero width space.
+func WrapInCodeBlock(text string) string {
+	return fmt.Sprintf("```\n%s\n```", strings.ReplaceAll(text, "```", "`\u200b``"))
+}
+
 func WrapInFat(text string) string {
 	return WrapInCustom(text, "**")
 }
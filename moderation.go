@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/roster"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/service"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// playerLookupTimeout is the time a server has to send its state in case a player is unknown to the roster.
+const playerLookupTimeout = 3 * time.Second
+
+// Kick kicks a player of the channel's linked server: !kick <name|id> [reason]
+func (b *Bot) Kick(msg *gateway.MessageCreateEvent, args bot.ArgumentParts) error {
+	return moderate(msg, config.ActionKick, args, false)
+}
+
+// Ban bans a player of the channel's linked server: !ban <name|id> [duration] [reason]
+func (b *Bot) Ban(msg *gateway.MessageCreateEvent, args bot.ArgumentParts) error {
+	return moderate(msg, config.ActionBan, args, true)
+}
+
+// Mute mutes a player of the channel's linked server: !mute <name|id> [duration] [reason]
+func (b *Bot) Mute(msg *gateway.MessageCreateEvent, args bot.ArgumentParts) error {
+	return moderate(msg, config.ActionMute, args, true)
+}
+
+// Spec moves a player of the channel's linked server to the spectators: !spec <name|id>
+func (b *Bot) Spec(msg *gateway.MessageCreateEvent, args bot.ArgumentParts) error {
+	return moderate(msg, config.ActionSpec, args, false)
+}
+
+// moderate resolves the player on the channel's linked server and executes the
+// configured command template of the moderation action.
+func moderate(msg *gateway.MessageCreateEvent, action string, args bot.ArgumentParts, withDuration bool) error {
+	if err := config.Modules().ErrIfDiscordLoggingDisabled(); err != nil {
+		return err
+	}
+	if err := errIfNotModerator(msg); err != nil {
+		return err
+	}
+	if args.Length() == 0 {
+		if withDuration {
+			return fmt.Errorf("usage: !%s <name|id> [duration] [reason]", action)
+		}
+		return fmt.Errorf("usage: !%s <name|id> [reason]", action)
+	}
+
+	econAddr, err := config.Discord().GetEconAddr(msg.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	player, err := resolvePlayer(econAddr, args.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	reasonIdx := 1
+	duration := time.Duration(0)
+	if withDuration && args.Length() > 1 {
+		if value, err := time.ParseDuration(args.Arg(1)); err == nil {
+			duration = value
+			reasonIdx = 2
+		}
+	}
+
+	command, err := config.Moderation().Command(action, player, econAddr, duration, args.After(reasonIdx))
+	if err != nil {
+		return err
+	}
+	service.Exec(*msg, econAddr, command)
+	return nil
+}
+
+// resolvePlayer finds the player by name or ID on the server. Players that are unknown to the roster,
+// e.g. because they joined before the bot was started, and players whose IP is unknown are looked up
+// in the state that is requested from the server.
+func resolvePlayer(econAddr, nameOrID string) (dto.Player, error) {
+	entry, err := roster.Find(econAddr, nameOrID)
+	if err == nil && entry.IP != "" {
+		return entry.Player, nil
+	} else if err != nil && !errors.Is(err, roster.ErrPlayerNotFound) {
+		return dto.Player{}, err
+	}
+
+	state, stateErr := service.RequestServerState(econAddr, playerLookupTimeout)
+	if stateErr == nil {
+		if player, found := findPlayer(state.Players, nameOrID); found {
+			return player, nil
+		}
+	}
+	if err == nil {
+		return entry.Player, nil
+	}
+
+	// the player can still be moderated via their ID
+	id, convErr := strconv.Atoi(strings.TrimPrefix(nameOrID, "#"))
+	if convErr != nil {
+		if stateErr != nil {
+			return dto.Player{}, fmt.Errorf("%s: %s", err, stateErr)
+		}
+		return dto.Player{}, err
+	}
+	return dto.Player{ID: id}, nil
+}
+
+// findPlayer returns the player with the ID or the case insensitive name.
+func findPlayer(players []dto.Player, nameOrID string) (dto.Player, bool) {
+	if id, err := strconv.Atoi(strings.TrimPrefix(nameOrID, "#")); err == nil {
+		for _, player := range players {
+			if player.ID == id {
+				return player, true
+			}
+		}
+	}
+	for _, player := range players {
+		if strings.EqualFold(player.Name, nameOrID) {
+			return player, true
+		}
+	}
+	return dto.Player{}, false
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/history"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/roster"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// Players lists the players of the channel's linked server or of the target alias, group or econ address.
+func (b *Bot) Players(msg *gateway.MessageCreateEvent, target ...string) (string, error) {
+	var (
+		econAddrs []string
+		err       error
+	)
+	if len(target) == 0 {
+		if config.Discord() == nil {
+			return "", config.Modules().ErrIfDiscordLoggingDisabled()
+		}
+		econAddrs = config.Discord().GetChannelEconAddrs(msg.ChannelID)
+		if len(econAddrs) == 0 {
+			return "", fmt.Errorf("this channel is not linked to any server, please specify a target")
+		}
+	} else {
+		econAddrs, err = config.Servers().Resolve(target[0])
+		if err != nil {
+			return "", err
+		}
+	}
+
+	sections := make([]string, 0, len(econAddrs))
+	for _, econAddr := range econAddrs {
+		players := roster.Players(econAddr)
+		lines := make([]string, 0, len(players)+1)
+		lines = append(lines, fmt.Sprintf("%s: %d players", markdown.WrapInFat(markdown.Escape(config.Servers().Alias(econAddr))), len(players)))
+		for _, player := range players {
+			lines = append(lines, fmtPlayer(player))
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+	return strings.Join(sections, "\n\n"), nil
+}
+
+// Online searches all servers for connected players whose names contain the passed name.
+func (b *Bot) Online(msg *gateway.MessageCreateEvent, name string) (string, error) {
+	players := roster.Search(name)
+	if len(players) == 0 {
+		return fmt.Sprintf("no player matching %s is online", markdown.WrapInInlineCodeBlock(name)), nil
+	}
+
+	lines := make([]string, 0, len(players))
+	for _, player := range players {
+		lines = append(lines, fmt.Sprintf("%s on %s", fmtPlayer(player), markdown.Escape(config.Servers().Alias(player.Server))))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func fmtPlayer(player roster.Entry) string {
+	return fmt.Sprintf(
+		"(%d) %s %s %s since %s",
+		player.ID,
+		markdown.Flag(player.Country),
+		markdown.WrapInInlineCodeBlock(player.Name),
+		markdown.WrapInInlineCodeBlock(player.Clan),
+		time.Since(player.Joined).Round(time.Second),
+	)
+}
+
+// Whois shows the history of a player name or an IP across all servers.
+// IPs are only shown in admin channels.
+func (b *Bot) Whois(msg *gateway.MessageCreateEvent, nameOrIP bot.RawArguments) (string, error) {
+	if err := config.Modules().ErrIfPlayerHistoryDisabled(); err != nil {
+		return "", err
+	}
+	query := strings.TrimSpace(string(nameOrIP))
+	if query == "" {
+		return "", fmt.Errorf("usage: !whois <name|ip>")
+	}
+	showIPs := config.Discord() != nil && config.Discord().IsAdminChannel(msg.ChannelID)
+
+	var (
+		record history.Record
+		err    error
+	)
+	isIP := net.ParseIP(query) != nil
+	if isIP {
+		if !showIPs {
+			return "", fmt.Errorf("IPs can only be looked up in admin channels")
+		}
+		record, err = history.LookupIP(query)
+	} else {
+		record, err = history.LookupName(query)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s: %s", markdown.WrapInInlineCodeBlock(query), err)
+	}
+
+	servers := make([]string, 0, len(record.Servers))
+	for _, econAddr := range record.Servers {
+		servers = append(servers, config.Servers().Alias(econAddr))
+	}
+
+	lines := []string{
+		markdown.WrapInFat(markdown.Escape(query)),
+		fmt.Sprintf("first seen: %s", record.FirstSeen.Format(time.RFC1123)),
+		fmt.Sprintf("last seen: %s", record.LastSeen.Format(time.RFC1123)),
+		fmt.Sprintf("servers: %s", markdown.Escape(strings.Join(servers, ", "))),
+	}
+	if isIP {
+		lines = append(lines, fmt.Sprintf("names: %s", fmtCodeList(record.Names)))
+	} else if showIPs {
+		lines = append(lines, fmt.Sprintf("IPs: %s", fmtCodeList(record.IPs)))
+	} else {
+		lines = append(lines, fmt.Sprintf("IPs: %d (only shown in admin channels)", len(record.IPs)))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func fmtCodeList(list []string) string {
+	wrapped := make([]string, 0, len(list))
+	for _, item := range list {
+		wrapped = append(wrapped, markdown.WrapInInlineCodeBlock(item))
+	}
+	return strings.Join(wrapped, ", ")
+}
@@ -12,7 +12,11 @@ import (
 	a "github.com/streadway/amqp"
 )
 
-func DiscordLog(ctx *bot.Context, channelID discord.ChannelID, eventType string, msg a.Delivery) error {
+func DiscordLog(ctx *bot.Context, channelIDs []discord.ChannelID, eventType string, msg a.Delivery) error {
+	if len(channelIDs) == 0 {
+		return nil
+	}
+
 	switch eventType {
 	case events.TypePlayerJoined, events.TypePlayerLeft:
 		if config.Discord().GetSkipJoinLeaveMessages() {
@@ -22,10 +26,39 @@ func DiscordLog(ctx *bot.Context, channelID discord.ChannelID, eventType string,
 		if config.Discord().GetSkipWhisperMessages() {
 			return nil
 		}
+	case events.TypeChatServer:
+		// only consumed in order to track the results of votes
+		return nil
+	}
+
+	event := events.BaseEvent{}
+	err := event.Unmarshal(string(msg.Body))
+	if err != nil {
+		return err
 	}
 
-	_, err := ctx.SendMessage(channelID, fmtEvent(eventType, msg.Body), nil)
-	return err
+	text := fmtEvent(eventType, msg.Body)
+	player, hasPlayer := eventPlayer(eventType, msg.Body)
+	for _, channelID := range channelIDs {
+		content := text
+		if config.Discord().IsSharedChannel(channelID) {
+			// multiple servers are logged to the same channel
+			content = fmt.Sprintf("%s %s", markdown.WrapInFat(markdown.Escape(config.Servers().Alias(event.EventSource))), text)
+		}
+		sent, err := ctx.SendMessage(channelID, content, nil)
+		if err != nil {
+			return err
+		}
+		if hasPlayer {
+			// allows moderators to react to the message in order to moderate the player
+			rememberSubject(sent.ID, Subject{
+				Server:  event.EventSource,
+				Player:  player,
+				Content: content,
+			})
+		}
+	}
+	return nil
 }
 
 func fmtEvent(eventType string, data []byte) string {
@@ -0,0 +1,90 @@
+package dclog
+
+import (
+	"sync"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+var (
+	// maxSubjects is the number of logged messages whose subject is remembered
+	maxSubjects = 4096
+
+	subjectsMu sync.Mutex
+	subjects   = make(map[discord.MessageID]Subject, maxSubjects)
+	// insertion order of the remembered messages, the oldest ones are forgotten first
+	subjectOrder = make([]discord.MessageID, 0, maxSubjects)
+)
+
+// Subject is the player and server that a logged discord message refers to.
+type Subject struct {
+	Server  string
+	Player  dto.Player
+	Content string
+}
+
+// SubjectOf returns the subject of a logged message, if it is still remembered.
+func SubjectOf(messageID discord.MessageID) (Subject, bool) {
+	subjectsMu.Lock()
+	defer subjectsMu.Unlock()
+	subject, found := subjects[messageID]
+	return subject, found
+}
+
+func rememberSubject(messageID discord.MessageID, subject Subject) {
+	subjectsMu.Lock()
+	defer subjectsMu.Unlock()
+
+	if len(subjectOrder) >= maxSubjects {
+		delete(subjects, subjectOrder[0])
+		subjectOrder = subjectOrder[1:]
+	}
+	subjects[messageID] = subject
+	subjectOrder = append(subjectOrder, messageID)
+}
+
+// eventPlayer returns the player that caused the event, e.g. the author of a
+// chat message or the player that started a vote.
+func eventPlayer(eventType string, data []byte) (dto.Player, bool) {
+	str := string(data)
+	switch eventType {
+	case events.TypeChat:
+		event := events.NewChatEvent()
+		if event.Unmarshal(str) == nil {
+			return event.Source, true
+		}
+	case events.TypeChatTeam:
+		event := events.NewChatTeamEvent()
+		if event.Unmarshal(str) == nil {
+			return event.Source, true
+		}
+	case events.TypeChatWhisper:
+		event := events.NewChatWhisperEvent()
+		if event.Unmarshal(str) == nil {
+			return event.Source, true
+		}
+	case events.TypeVoteKickStarted:
+		event := events.VoteKickStartedEvent{}
+		if event.Unmarshal(str) == nil {
+			return event.Source, true
+		}
+	case events.TypeVoteSpecStarted:
+		event := events.VoteSpecStartedEvent{}
+		if event.Unmarshal(str) == nil {
+			return event.Source, true
+		}
+	case events.TypeVoteOptionStarted:
+		event := events.VoteOptionStartedEvent{}
+		if event.Unmarshal(str) == nil {
+			return event.Source, true
+		}
+	case events.TypePlayerJoined:
+		event := events.NewPlayerJoinedEvent()
+		if event.Unmarshal(str) == nil {
+			return event.Player, true
+		}
+	}
+	return dto.Player{}, false
+}
@@ -0,0 +1,16 @@
+package processors
+
+import (
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/events"
+)
+
+// EventTime parses the timestamp of an event, the current time is used for invalid timestamps.
+func EventTime(timestamp string) time.Time {
+	t, err := events.ParseTimestamp(timestamp)
+	if err != nil {
+		return time.Now()
+	}
+	return t
+}
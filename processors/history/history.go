@@ -0,0 +1,131 @@
+package history
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/go-redis/redis"
+	a "github.com/streadway/amqp"
+)
+
+const (
+	namePrefix   = "history:name:"
+	ipPrefix     = "history:ip:"
+	firstSeenKey = "first_seen"
+	lastSeenKey  = "last_seen"
+)
+
+var (
+	// ErrNotFound is returned if a player name or an IP has never been seen before.
+	ErrNotFound = errors.New("no history found")
+)
+
+// Record is the history of either a player name or an IP.
+type Record struct {
+	FirstSeen time.Time
+	LastSeen  time.Time
+	// Names used by an IP, empty for name records
+	Names []string
+	// IPs used by a name, empty for IP records
+	IPs     []string
+	Servers []string
+}
+
+// Store fills the player history with joining and leaving players.
+func Store(ctx *bot.Context, channelIDs []discord.ChannelID, eventType string, msg a.Delivery) error {
+	switch eventType {
+	case events.TypePlayerJoined:
+		event := events.NewPlayerJoinedEvent()
+		err := event.Unmarshal(string(msg.Body))
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal PlayerJoinedEvent: %s", err)
+		}
+		return seen(event.Player, event.EventSource, true)
+	case events.TypePlayerLeft:
+		event := events.NewPlayerLeftEvent()
+		err := event.Unmarshal(string(msg.Body))
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal PlayerLeftEvent: %s", err)
+		}
+		return seen(event.Player, event.EventSource, false)
+	}
+	return nil
+}
+
+func seen(player dto.Player, econAddr string, joined bool) error {
+	if normalized, err := config.NormalizeAddress(econAddr); err == nil {
+		econAddr = normalized
+	}
+	now := time.Now().Format(time.RFC3339)
+	nameKey := namePrefix + player.Name
+	ipKey := ipPrefix + player.IP
+
+	_, err := config.History().RDB().TxPipelined(func(tx redis.Pipeliner) error {
+		tx.HSetNX(nameKey, firstSeenKey, now)
+		tx.HSet(nameKey, lastSeenKey, now)
+		if joined {
+			tx.SAdd(nameKey+":servers", econAddr)
+		}
+		if player.IP == "" {
+			return nil
+		}
+		tx.HSetNX(ipKey, firstSeenKey, now)
+		tx.HSet(ipKey, lastSeenKey, now)
+		if joined {
+			tx.SAdd(nameKey+":ips", player.IP)
+			tx.SAdd(ipKey+":names", player.Name)
+			tx.SAdd(ipKey+":servers", econAddr)
+		}
+		return nil
+	})
+	return err
+}
+
+// LookupName returns the history of a player name.
+func LookupName(name string) (Record, error) {
+	return lookup(namePrefix+name, ":ips")
+}
+
+// LookupIP returns the history of an IP.
+func LookupIP(ip string) (Record, error) {
+	return lookup(ipPrefix+ip, ":names")
+}
+
+func lookup(key, relatedSuffix string) (Record, error) {
+	rdb := config.History().RDB()
+	times, err := rdb.HGetAll(key).Result()
+	if err != nil {
+		return Record{}, err
+	}
+	if len(times) == 0 {
+		return Record{}, ErrNotFound
+	}
+
+	related, err := rdb.SMembers(key + relatedSuffix).Result()
+	if err != nil {
+		return Record{}, err
+	}
+	servers, err := rdb.SMembers(key + ":servers").Result()
+	if err != nil {
+		return Record{}, err
+	}
+	sort.Strings(related)
+	sort.Strings(servers)
+
+	record := Record{Servers: servers}
+	record.FirstSeen, _ = time.Parse(time.RFC3339, times[firstSeenKey])
+	record.LastSeen, _ = time.Parse(time.RFC3339, times[lastSeenKey])
+	if relatedSuffix == ":ips" {
+		record.IPs = related
+	} else {
+		record.Names = related
+	}
+	return record, nil
+}
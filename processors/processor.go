@@ -7,4 +7,5 @@ import (
 )
 
 // EventProcessor is a function that can process events
-type EventProcessor func(ctx *bot.Context, channelID discord.ChannelID, eventType string, msg a.Delivery) error
+// channelIDs contains all channels that the event is routed to, it may be empty.
+type EventProcessor func(ctx *bot.Context, channelIDs []discord.ChannelID, eventType string, msg a.Delivery) error
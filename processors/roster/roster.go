@@ -0,0 +1,192 @@
+package roster
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/discord"
+	a "github.com/streadway/amqp"
+)
+
+var (
+	// ErrPlayerNotFound is returned if no connected player matches the passed name or ID.
+	ErrPlayerNotFound = errors.New("player not found")
+
+	mu sync.RWMutex
+	// econ address -> player ID -> player
+	servers = make(map[string]map[int]Entry)
+)
+
+// Entry is a player that is currently connected to a server.
+type Entry struct {
+	dto.Player
+	Server string
+	Joined time.Time
+}
+
+// Track keeps track of the players that are currently connected to the servers.
+func Track(ctx *bot.Context, channelIDs []discord.ChannelID, eventType string, msg a.Delivery) error {
+	switch eventType {
+	case events.TypePlayerJoined:
+		event := events.NewPlayerJoinedEvent()
+		err := event.Unmarshal(string(msg.Body))
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal PlayerJoinedEvent: %s", err)
+		}
+		join(serverKey(event.EventSource), event.Player, processors.EventTime(event.Timestamp))
+	case events.TypePlayerLeft:
+		event := events.NewPlayerLeftEvent()
+		err := event.Unmarshal(string(msg.Body))
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal PlayerLeftEvent: %s", err)
+		}
+		leave(serverKey(event.EventSource), event.Player)
+	case events.TypeMapChanged:
+		event := events.NewMapChangedEvent()
+		err := event.Unmarshal(string(msg.Body))
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal MapChangedEvent: %s", err)
+		}
+		// the initial map of a server does not have a previous map,
+		// meaning that the server has been (re)started and all previous players are gone.
+		if event.OldMap == "" {
+			reset(serverKey(event.EventSource))
+		}
+	}
+	return nil
+}
+
+// serverKey uses the normalized econ address in order to match the addresses of the configuration.
+func serverKey(econAddr string) string {
+	normalized, err := config.NormalizeAddress(econAddr)
+	if err != nil {
+		return econAddr
+	}
+	return normalized
+}
+
+func join(econAddr string, player dto.Player, joined time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+	players, found := servers[econAddr]
+	if !found {
+		players = make(map[int]Entry)
+		servers[econAddr] = players
+	}
+	// a player that is still known with the same ID must have left without us noticing.
+	players[player.ID] = Entry{
+		Player: player,
+		Server: econAddr,
+		Joined: joined,
+	}
+}
+
+func leave(econAddr string, player dto.Player) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(servers[econAddr], player.ID)
+}
+
+func reset(econAddr string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(servers, econAddr)
+}
+
+// Servers returns the sorted econ addresses of all servers that have connected players.
+func Servers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	addrs := make([]string, 0, len(servers))
+	for addr, players := range servers {
+		if len(players) > 0 {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// Players returns the players that are connected to the server sorted by their ID.
+func Players(econAddr string) []Entry {
+	econAddr = serverKey(econAddr)
+
+	mu.RLock()
+	defer mu.RUnlock()
+	players := make([]Entry, 0, len(servers[econAddr]))
+	for _, player := range servers[econAddr] {
+		players = append(players, player)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].ID < players[j].ID
+	})
+	return players
+}
+
+// Player returns the player with the given ID of the server.
+func Player(econAddr string, id int) (Entry, bool) {
+	econAddr = serverKey(econAddr)
+
+	mu.RLock()
+	defer mu.RUnlock()
+	player, found := servers[econAddr][id]
+	return player, found
+}
+
+// Find searches a connected player on the server by either their ID or their name.
+// Names are matched case insensitively, first exactly and then as unique prefix.
+func Find(econAddr, nameOrID string) (Entry, error) {
+	if id, err := strconv.Atoi(strings.TrimPrefix(nameOrID, "#")); err == nil {
+		if player, found := Player(econAddr, id); found {
+			return player, nil
+		}
+	}
+
+	matches := make([]Entry, 0, 1)
+	for _, player := range Players(econAddr) {
+		if strings.EqualFold(player.Name, nameOrID) {
+			return player, nil
+		}
+		if strings.HasPrefix(strings.ToLower(player.Name), strings.ToLower(nameOrID)) {
+			matches = append(matches, player)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Entry{}, fmt.Errorf("%w: %s", ErrPlayerNotFound, nameOrID)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, 0, len(matches))
+		for _, player := range matches {
+			names = append(names, fmt.Sprintf("%s (%d)", player.Name, player.ID))
+		}
+		return Entry{}, fmt.Errorf("ambiguous player %s, matches: %s", nameOrID, strings.Join(names, ", "))
+	}
+}
+
+// Search returns all connected players of all servers whose names contain the passed name case insensitively.
+// The result is sorted by server and player ID.
+func Search(name string) []Entry {
+	name = strings.ToLower(name)
+	result := make([]Entry, 0)
+	for _, addr := range Servers() {
+		for _, player := range Players(addr) {
+			if strings.Contains(strings.ToLower(player.Name), name) {
+				result = append(result, player)
+			}
+		}
+	}
+	return result
+}
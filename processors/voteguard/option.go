@@ -0,0 +1,180 @@
+package voteguard
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/discord"
+	a "github.com/streadway/amqp"
+)
+
+var (
+	optionMu sync.Mutex
+	// econ address -> option -> time of the last passed vote of any player
+	lastOptionVotes = make(map[string]map[string]time.Time)
+	// econ address -> option -> player name -> time of the player's last passed vote
+	lastPlayerOptionVotes = make(map[string]map[string]map[string]time.Time)
+	// econ address -> running option vote whose cooldowns start once it passes
+	runningOptionVotes = make(map[string]optionVote)
+)
+
+type optionVote struct {
+	name   string
+	option string
+}
+
+// guardOption aborts option votes that violate the cooldowns of the matching vote option rules.
+func guardOption(ctx *bot.Context, channelIDs []discord.ChannelID, msg a.Delivery) error {
+	event := events.VoteOptionStartedEvent{}
+	err := event.Unmarshal(string(msg.Body))
+	if err != nil {
+		return fmt.Errorf("unable to unmarshal VoteOptionStartedEvent: %s", err)
+	}
+	if event.Try {
+		// the server did not start the vote
+		return nil
+	}
+	econAddr := event.EventSource
+	if normalized, err := config.NormalizeAddress(econAddr); err == nil {
+		econAddr = normalized
+	}
+	started := processors.EventTime(event.Timestamp)
+	vote := optionVote{
+		name:   event.Source.Name,
+		option: normalizeOption(event.Option),
+	}
+
+	rules := config.VoteGuard().OptionRules(econAddr, event.Option)
+	if len(rules) == 0 {
+		return nil
+	}
+
+	if event.Forced {
+		// forced votes pass immediately and cannot be aborted
+		passOption(econAddr, vote, started)
+		return nil
+	}
+
+	reason, abort := checkOption(econAddr, vote, rules, started)
+	if !abort {
+		startOption(econAddr, vote)
+		return nil
+	}
+
+	err = config.VoteGuard().RequestAbort(econAddr, reason)
+	if err != nil {
+		return fmt.Errorf("failed to abort vote: %s", err)
+	}
+
+	text := fmt.Sprintf(
+		"[vote guard] aborted vote of %s (%d) for option %s: %s",
+		markdown.WrapInInlineCodeBlock(event.Source.Name),
+		event.Source.ID,
+		markdown.WrapInInlineCodeBlock(event.Option),
+		markdown.Escape(reason),
+	)
+	report(ctx, econAddr, text)
+	return send(ctx, channelIDs, text)
+}
+
+// trackVoteResult starts the cooldowns of the running option vote once the server announces
+// that it passed. Failed and aborted votes do not start any cooldowns.
+func trackVoteResult(msg a.Delivery) error {
+	event := events.ChatServerEvent{}
+	err := event.Unmarshal(string(msg.Body))
+	if err != nil {
+		return fmt.Errorf("unable to unmarshal ChatServerEvent: %s", err)
+	}
+	text := strings.ToLower(event.Text)
+	passed := strings.Contains(text, "vote passed")
+	if !passed && !strings.Contains(text, "vote failed") && !strings.Contains(text, "vote aborted") {
+		return nil
+	}
+
+	econAddr := event.EventSource
+	if normalized, err := config.NormalizeAddress(econAddr); err == nil {
+		econAddr = normalized
+	}
+
+	optionMu.Lock()
+	vote, found := runningOptionVotes[econAddr]
+	delete(runningOptionVotes, econAddr)
+	optionMu.Unlock()
+
+	if found && passed {
+		passOption(econAddr, vote, processors.EventTime(event.Timestamp))
+	}
+	return nil
+}
+
+func normalizeOption(option string) string {
+	return strings.ToLower(strings.TrimSpace(option))
+}
+
+// checkOption returns the reason why the option vote violates any of the rules.
+func checkOption(econAddr string, vote optionVote, rules []config.VoteOptionRule, started time.Time) (string, bool) {
+	optionMu.Lock()
+	defer optionMu.Unlock()
+
+	for _, rule := range rules {
+		if last, found := lastOptionVotes[econAddr][vote.option]; found && started.Sub(last) < rule.GlobalCooldown {
+			return fmt.Sprintf("%s can be voted again in %s", vote.option, fmtWait(rule.GlobalCooldown-started.Sub(last))), true
+		}
+		if last, found := lastPlayerOptionVotes[econAddr][vote.option][vote.name]; found && started.Sub(last) < rule.PlayerCooldown {
+			return fmt.Sprintf("%s can vote %s again in %s", vote.name, vote.option, fmtWait(rule.PlayerCooldown-started.Sub(last))), true
+		}
+	}
+	return "", false
+}
+
+// startOption remembers the option vote until the server announces its result.
+// A server runs only one vote at a time.
+func startOption(econAddr string, vote optionVote) {
+	optionMu.Lock()
+	defer optionMu.Unlock()
+	runningOptionVotes[econAddr] = vote
+}
+
+// forgetOption forgets the running option vote without starting its cooldowns.
+func forgetOption(econAddr string) {
+	optionMu.Lock()
+	defer optionMu.Unlock()
+	delete(runningOptionVotes, econAddr)
+}
+
+// passOption starts the cooldowns of the option.
+func passOption(econAddr string, vote optionVote, passed time.Time) {
+	optionMu.Lock()
+	defer optionMu.Unlock()
+
+	global, found := lastOptionVotes[econAddr]
+	if !found {
+		global = make(map[string]time.Time)
+		lastOptionVotes[econAddr] = global
+	}
+	global[vote.option] = passed
+
+	perOption, found := lastPlayerOptionVotes[econAddr]
+	if !found {
+		perOption = make(map[string]map[string]time.Time)
+		lastPlayerOptionVotes[econAddr] = perOption
+	}
+	players, found := perOption[vote.option]
+	if !found {
+		players = make(map[string]time.Time)
+		perOption[vote.option] = players
+	}
+	players[vote.name] = passed
+}
+
+// fmtWait rounds the remaining time up to full seconds
+func fmtWait(d time.Duration) string {
+	return (d + time.Second - 1).Truncate(time.Second).String()
+}
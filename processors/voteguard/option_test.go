@@ -0,0 +1,99 @@
+package voteguard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	a "github.com/streadway/amqp"
+)
+
+func TestCheckOption(t *testing.T) {
+	passed := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	rules := []config.VoteOptionRule{{
+		Target:         "*",
+		Pattern:        "change map*",
+		PlayerCooldown: 10 * time.Minute,
+		GlobalCooldown: time.Minute,
+	}}
+
+	tests := []struct {
+		name      string
+		econAddr  string
+		vote      optionVote
+		started   time.Time
+		wantAbort bool
+	}{
+		{"global cooldown", econAddr, optionVote{"b", "change map ctf5"}, passed.Add(30 * time.Second), true},
+		{"global cooldown over", econAddr, optionVote{"b", "change map ctf5"}, passed.Add(time.Minute), false},
+		{"player cooldown", econAddr, optionVote{"a", "change map ctf5"}, passed.Add(5 * time.Minute), true},
+		{"player cooldown over", econAddr, optionVote{"a", "change map ctf5"}, passed.Add(10 * time.Minute), false},
+		{"other option", econAddr, optionVote{"a", "change map dm1"}, passed.Add(time.Second), false},
+		{"other server", "127.0.0.1:8304", optionVote{"a", "change map ctf5"}, passed.Add(time.Second), false},
+	}
+	reset()
+	passOption(econAddr, optionVote{"a", "change map ctf5"}, passed)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason, abort := checkOption(tt.econAddr, tt.vote, rules, tt.started)
+			if abort != tt.wantAbort {
+				t.Errorf("checkOption() = %q, %v, want abort %v", reason, abort, tt.wantAbort)
+			}
+		})
+	}
+}
+
+func TestTrackVoteResult(t *testing.T) {
+	vote := optionVote{"a", "change map ctf5"}
+	tests := []struct {
+		name        string
+		text        string
+		wantPassed  bool
+		wantRunning bool
+	}{
+		{"passed", "Vote passed", true, false},
+		{"failed", "Vote failed", false, false},
+		{"aborted", "Vote aborted", false, false},
+		{"unrelated", "'a' called for vote to change server option 'change map ctf5'", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			startOption(econAddr, vote)
+
+			event := events.NewChatServerEvent()
+			event.EventSource = econAddr
+			event.Text = tt.text
+			err := trackVoteResult(a.Delivery{Body: []byte(event.Marshal())})
+			if err != nil {
+				t.Fatalf("trackVoteResult() unexpected error: %v", err)
+			}
+
+			if _, passed := lastOptionVotes[econAddr][vote.option]; passed != tt.wantPassed {
+				t.Errorf("cooldown started = %v, want %v", passed, tt.wantPassed)
+			}
+			if _, running := runningOptionVotes[econAddr]; running != tt.wantRunning {
+				t.Errorf("vote running = %v, want %v", running, tt.wantRunning)
+			}
+		})
+	}
+}
+
+func TestFmtWait(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{time.Second, "1s"},
+		{1500 * time.Millisecond, "2s"},
+		{time.Minute + time.Nanosecond, "1m1s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := fmtWait(tt.d); got != tt.want {
+				t.Errorf("fmtWait(%s) = %s, want %s", tt.d, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package voteguard
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/roster"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/service"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/discord"
+	a "github.com/streadway/amqp"
+)
+
+var (
+	mu sync.Mutex
+	// econ address -> player -> start times of the player's recent votes
+	votes = make(map[string]map[string][]time.Time)
+)
+
+// Guard aborts kick- and specvotes against protected players as well as votes of
+// players that joined recently or started too many votes.
+// Tries did not start a vote and forced votes pass immediately, both are ignored.
+// Option votes are aborted if they violate the cooldowns of the vote option rules,
+// the cooldowns start once an option vote passed.
+func Guard(ctx *bot.Context, channelIDs []discord.ChannelID, eventType string, msg a.Delivery) error {
+	var (
+		econAddr string
+		source   dto.Player
+		target   dto.Player
+		started  time.Time
+	)
+	switch eventType {
+	case events.TypeVoteKickStarted:
+		event := events.VoteKickStartedEvent{}
+		err := event.Unmarshal(string(msg.Body))
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal VoteKickStartedEvent: %s", err)
+		}
+		if event.Try || event.Forced {
+			return nil
+		}
+		econAddr, source, target, started = event.EventSource, event.Source, event.Target, processors.EventTime(event.Timestamp)
+	case events.TypeVoteSpecStarted:
+		event := events.VoteSpecStartedEvent{}
+		err := event.Unmarshal(string(msg.Body))
+		if err != nil {
+			return fmt.Errorf("unable to unmarshal VoteSpecStartedEvent: %s", err)
+		}
+		if event.Try || event.Forced {
+			return nil
+		}
+		econAddr, source, target, started = event.EventSource, event.Source, event.Target, processors.EventTime(event.Timestamp)
+	case events.TypeVoteOptionStarted:
+		return guardOption(ctx, channelIDs, msg)
+	case events.TypeChatServer:
+		return trackVoteResult(msg)
+	default:
+		return nil
+	}
+	if normalized, err := config.NormalizeAddress(econAddr); err == nil {
+		econAddr = normalized
+	}
+	// a new vote can only start once the previous one ended
+	forgetOption(econAddr)
+
+	reason, abort := check(econAddr, complete(econAddr, source), complete(econAddr, target), started)
+	if !abort {
+		return nil
+	}
+
+	err := config.VoteGuard().RequestAbort(econAddr, reason)
+	if err != nil {
+		return fmt.Errorf("failed to abort vote: %s", err)
+	}
+
+	text := fmt.Sprintf(
+		"[vote guard] aborted vote of %s (%d) against %s (%d): %s",
+		markdown.WrapInInlineCodeBlock(source.Name),
+		source.ID,
+		markdown.WrapInInlineCodeBlock(target.Name),
+		target.ID,
+		markdown.Escape(reason),
+	)
+	report(ctx, econAddr, text)
+	return send(ctx, channelIDs, text)
+}
+
+// report adds the aborted vote to the audit trail, as the events of the server
+// might not be routed to any channel.
+func report(ctx *bot.Context, econAddr, text string) {
+	service.Audit(ctx, "%s on %s", text, markdown.Escape(config.Servers().Alias(econAddr)))
+}
+
+func send(ctx *bot.Context, channelIDs []discord.ChannelID, text string) error {
+	for _, channelID := range channelIDs {
+		_, err := ctx.SendMessage(channelID, text, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns the reason why the vote needs to be aborted.
+func check(econAddr string, source roster.Entry, target roster.Entry, started time.Time) (string, bool) {
+	maxVotes, window := config.VoteGuard().VoteRate()
+	// the vote is recorded in any case, as aborted votes are votes as well
+	count := record(econAddr, source.Name, started, window)
+
+	if reason, protected := config.VoteGuard().IsProtected(target.Name, target.Clan, target.IP); protected {
+		return reason, true
+	}
+
+	minJoinAge := config.VoteGuard().MinJoinAge()
+	if minJoinAge > 0 && !source.Joined.IsZero() && started.Sub(source.Joined) < minJoinAge {
+		return fmt.Sprintf("%s joined less than %s ago", source.Name, minJoinAge), true
+	}
+
+	if maxVotes > 0 && count > maxVotes {
+		return fmt.Sprintf("%s started more than %d votes within %s", source.Name, maxVotes, window), true
+	}
+	return "", false
+}
+
+// record adds the vote to the recent votes of the player and returns the number of
+// votes the player started within the window.
+func record(econAddr, name string, started time.Time, window time.Duration) int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	prune(started, window)
+	players, found := votes[econAddr]
+	if !found {
+		players = make(map[string][]time.Time)
+		votes[econAddr] = players
+	}
+
+	recent := make([]time.Time, 0, len(players[name])+1)
+	for _, t := range players[name] {
+		if started.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, started)
+	players[name] = recent
+	return len(recent)
+}
+
+// prune forgets the votes that are outside of the window, expects the lock to be held.
+func prune(now time.Time, window time.Duration) {
+	for econAddr, players := range votes {
+		for name, times := range players {
+			if len(times) == 0 || now.Sub(times[len(times)-1]) >= window {
+				delete(players, name)
+			}
+		}
+		if len(players) == 0 {
+			delete(votes, econAddr)
+		}
+	}
+}
+
+// complete adds the roster data like the IP and join time to the player of a vote event.
+func complete(econAddr string, player dto.Player) roster.Entry {
+	entry, found := roster.Player(econAddr, player.ID)
+	if found && entry.Name == player.Name {
+		return entry
+	}
+	return roster.Entry{
+		Player: player,
+		Server: econAddr,
+	}
+}
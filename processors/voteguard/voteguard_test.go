@@ -0,0 +1,109 @@
+package voteguard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	a "github.com/streadway/amqp"
+)
+
+const econAddr = "127.0.0.1:8303"
+
+// reset forgets all recorded votes of previous tests.
+func reset() {
+	votes = make(map[string]map[string][]time.Time)
+	lastOptionVotes = make(map[string]map[string]time.Time)
+	lastPlayerOptionVotes = make(map[string]map[string]map[string]time.Time)
+	runningOptionVotes = make(map[string]optionVote)
+}
+
+func TestGuardIgnoresTriesAndForcedVotes(t *testing.T) {
+	source := dto.Player{Name: "source", ID: 1}
+	target := dto.Player{Name: "target", ID: 2}
+
+	kick := func(try, forced bool) (string, string) {
+		event := events.NewVoteKickStartedEvent()
+		event.EventSource = econAddr
+		event.Source, event.Target, event.Try, event.Forced = source, target, try, forced
+		return events.TypeVoteKickStarted, event.Marshal()
+	}
+	spec := func(try, forced bool) (string, string) {
+		event := events.NewVoteSpecStartedEvent()
+		event.EventSource = econAddr
+		event.Source, event.Target, event.Try, event.Forced = source, target, try, forced
+		return events.TypeVoteSpecStarted, event.Marshal()
+	}
+	option := func(try, forced bool) (string, string) {
+		event := events.NewVoteOptionStartedEvent()
+		event.EventSource = econAddr
+		event.Source, event.Option, event.Try, event.Forced = source, "ctf5", try, forced
+		return events.TypeVoteOptionStarted, event.Marshal()
+	}
+
+	tests := []struct {
+		name  string
+		event func(try, forced bool) (string, string)
+		try   bool
+		force bool
+	}{
+		{"kick try", kick, true, false},
+		{"forced kick", kick, false, true},
+		{"spec try", spec, true, false},
+		{"forced spec", spec, false, true},
+		{"option try", option, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			running := optionVote{name: "other", option: "dm1"}
+			runningOptionVotes[econAddr] = running
+
+			eventType, payload := tt.event(tt.try, tt.force)
+			err := Guard(nil, nil, eventType, a.Delivery{Body: []byte(payload)})
+			if err != nil {
+				t.Fatalf("Guard() unexpected error: %v", err)
+			}
+			if len(votes) != 0 {
+				t.Errorf("Guard() recorded votes: %v", votes)
+			}
+			if got := runningOptionVotes[econAddr]; got != running {
+				t.Errorf("Guard() changed the running option vote to %v, want %v", got, running)
+			}
+		})
+	}
+}
+
+func TestRecord(t *testing.T) {
+	start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	window := time.Minute
+
+	tests := []struct {
+		name     string
+		econAddr string
+		player   string
+		started  time.Time
+		want     int
+	}{
+		{"first vote", econAddr, "a", start, 1},
+		{"second vote", econAddr, "a", start.Add(10 * time.Second), 2},
+		{"other player", econAddr, "b", start.Add(20 * time.Second), 1},
+		{"other server", "127.0.0.1:8304", "a", start.Add(30 * time.Second), 1},
+		{"first vote left the window", econAddr, "a", start.Add(window + 5*time.Second), 2},
+		{"all votes left the window", econAddr, "a", start.Add(3 * window), 1},
+	}
+	reset()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := record(tt.econAddr, tt.player, tt.started, window); got != tt.want {
+				t.Errorf("record() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	// the votes of the other player and server were pruned
+	if len(votes) != 1 || len(votes[econAddr]) != 1 {
+		t.Errorf("votes were not pruned: %v", votes)
+	}
+}
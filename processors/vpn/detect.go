@@ -1,19 +1,19 @@
 package vpn
 
 import (
-	"errors"
 	"fmt"
 	"log"
 
 	"github.com/Teeworlds-Server-Moderation/common/events"
 	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/service"
 	"github.com/diamondburned/arikawa/v2/bot"
 	"github.com/diamondburned/arikawa/v2/discord"
-	"github.com/jxsl13/goripr"
 	a "github.com/streadway/amqp"
 )
 
-func Detect(ctx *bot.Context, channelID discord.ChannelID, eventType string, message a.Delivery) error {
+func Detect(ctx *bot.Context, channelIDs []discord.ChannelID, eventType string, message a.Delivery) error {
 	if eventType != events.TypePlayerJoined {
 		return nil
 	}
@@ -22,20 +22,59 @@ func Detect(ctx *bot.Context, channelID discord.ChannelID, eventType string, mes
 	if err != nil {
 		return fmt.Errorf("unable to unmarshal PlayerJoinedEvent: %s", err)
 	}
-	ripr := config.DetectVPN().RDB()
-
 	log.Printf("Trying to find: '%s'\n", event.IP)
-	reason, err := ripr.Find(event.IP)
-
-	if errors.Is(goripr.ErrIPNotFound, err) {
+	verdict, err := Lookup(event.IP)
+	if err != nil {
+		return fmt.Errorf("unexpected error occurred: %s", err)
+	}
+	if !verdict.VPN {
 		log.Printf("[NO VPN]: %s\n", event.IP)
 		return nil
-	} else if err != nil {
-		return fmt.Errorf("unexpected error occurred: %s", err)
 	}
-	if err := config.DetectVPN().RequestBan(event.Player, reason, event.EventSource); err != nil {
+
+	reason := verdict.Reason
+	if reason == "" {
+		reason = config.DetectVPN().BanReason()
+	}
+	policy := config.DetectVPN().Policy(event.EventSource)
+	if policy.Action == config.PolicyExempt {
+		log.Printf("[IS VPN, EXEMPT]: %s on %s\n", event.IP, event.EventSource)
+		return nil
+	}
+	if policy.Reason != "" {
+		reason = policy.Reason
+	}
+	verdict.Reason = reason
+	detection := Detection{
+		Server:  event.EventSource,
+		Player:  event.Player,
+		Verdict: verdict,
+		Action:  policy.Action,
+	}
+
+	if config.DetectVPN().IsDryRun(event.EventSource) {
+		detection.DryRun = true
+		detection.Command, err = config.DetectVPN().BuildBanCommand(policy, event.Player, reason, verdict.Range, event.EventSource)
+		if err != nil {
+			return err
+		}
+		recordDryRun(event.EventSource, event.IP)
+		notify(ctx, channelIDs, detection)
+		service.Audit(ctx, "[DRY RUN]: %s would have been %s on %s: %s",
+			markdown.WrapInInlineCodeBlock(event.Player.Name),
+			fmtAction(policy.Action),
+			fmtServer(event.EventSource),
+			markdown.WrapInInlineCodeBlock(detection.Command),
+		)
+		log.Printf("[IS VPN, DRY RUN]: %s (%s, confidence %.2f)\n", event.IP, verdict.Provider, verdict.Confidence)
+		return nil
+	}
+
+	detection.Command, err = config.DetectVPN().RequestBan(policy, event.Player, reason, verdict.Range, event.EventSource)
+	if err != nil {
 		return err
 	}
-	log.Printf("[IS VPN]: %s\n", event.IP)
+	notify(ctx, channelIDs, detection)
+	log.Printf("[IS VPN]: %s (%s, confidence %.2f)\n", event.IP, verdict.Provider, verdict.Confidence)
 	return nil
 }
@@ -0,0 +1,41 @@
+package vpn
+
+import (
+	"sync"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+)
+
+var (
+	dryRunMu sync.Mutex
+	// econ address -> IPs of the players that would have been punished since the start,
+	// players that rejoin are counted only once
+	dryRunHits = make(map[string]map[string]bool)
+)
+
+func recordDryRun(econAddr, ip string) {
+	if normalized, err := config.NormalizeAddress(econAddr); err == nil {
+		econAddr = normalized
+	}
+
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+	ips, found := dryRunHits[econAddr]
+	if !found {
+		ips = make(map[string]bool)
+		dryRunHits[econAddr] = ips
+	}
+	ips[ip] = true
+}
+
+// DryRunHits returns the number of unique IPs of detected VPN players per server that
+// were only reported because of the dry run since the start.
+func DryRunHits() map[string]int {
+	dryRunMu.Lock()
+	defer dryRunMu.Unlock()
+	result := make(map[string]int, len(dryRunHits))
+	for econAddr, ips := range dryRunHits {
+		result[econAddr] = len(ips)
+	}
+	return result
+}
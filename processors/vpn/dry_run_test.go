@@ -0,0 +1,49 @@
+package vpn
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDryRunHits(t *testing.T) {
+	type hit struct {
+		econAddr string
+		ip       string
+	}
+	tests := []struct {
+		name string
+		hits []hit
+		want map[string]int
+	}{
+		{"none", nil, map[string]int{}},
+		{"single", []hit{{"127.0.0.1:8303", "1.2.3.4"}}, map[string]int{"127.0.0.1:8303": 1}},
+		{"rejoin", []hit{
+			{"127.0.0.1:8303", "1.2.3.4"},
+			{"127.0.0.1:8303", "1.2.3.4"},
+		}, map[string]int{"127.0.0.1:8303": 1}},
+		{"different IPs", []hit{
+			{"127.0.0.1:8303", "1.2.3.4"},
+			{"127.0.0.1:8303", "1.2.3.5"},
+		}, map[string]int{"127.0.0.1:8303": 2}},
+		{"same IP on different servers", []hit{
+			{"127.0.0.1:8303", "1.2.3.4"},
+			{"127.0.0.1:8304", "1.2.3.4"},
+		}, map[string]int{"127.0.0.1:8303": 1, "127.0.0.1:8304": 1}},
+		{"normalized address", []hit{
+			{"[0:0::1]:8303", "1.2.3.4"},
+			{"[::1]:08303", "1.2.3.4"},
+			{"[::1]:8303", "::2"},
+		}, map[string]int{"[::1]:8303": 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dryRunHits = make(map[string]map[string]bool)
+			for _, h := range tt.hits {
+				recordDryRun(h.econAddr, h.ip)
+			}
+			if got := DryRunHits(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DryRunHits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package vpn
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/service"
+	"github.com/diamondburned/arikawa/v2/bot"
+)
+
+// FetchPeriodically downloads the remote blacklists at startup and in the configured interval
+// and imports them in case any of them changed.
+func FetchPeriodically(ctx *bot.Context) {
+	if len(config.DetectVPN().BlacklistURLs()) == 0 {
+		return
+	}
+	interval := config.DetectVPN().FetchInterval()
+	log.Printf("Fetching remote blacklists every %s...\n", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := Fetch(ctx)
+		if err != nil {
+			log.Printf("failed to fetch remote blacklists: %s\n", err)
+		}
+		select {
+		case <-service.Done():
+			log.Println("Stopped fetching remote blacklists...")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Fetch downloads the remote blacklists, reports failures in the VPN channel
+// and imports the updated blacklists.
+func Fetch(ctx *bot.Context) error {
+	results, err := config.DetectVPN().FetchBlacklists()
+	if err != nil {
+		announce(ctx, fmt.Sprintf("[ERROR]: failed to fetch remote blacklists: %s", err))
+		return err
+	}
+
+	failed := make([]string, 0)
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			log.Printf("failed to fetch blacklist %s: %s\n", result.URL, result.Err)
+			failed = append(failed, fmt.Sprintf("%s: %s", markdown.WrapInInlineCodeBlock(result.URL), markdown.Escape(result.Err.Error())))
+		case result.Updated:
+			log.Printf("Downloaded %d ranges from: %s\n", result.Ranges, result.URL)
+		}
+	}
+	if len(failed) > 0 {
+		announce(ctx, fmt.Sprintf("[ERROR]: failed to fetch remote blacklists, keeping the last downloaded versions:\n%s", strings.Join(failed, "\n")))
+	}
+
+	// imports updated and removes no longer configured blacklists
+	_, err = Reload(ctx)
+	return err
+}
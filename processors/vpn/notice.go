@@ -0,0 +1,162 @@
+package vpn
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/discord"
+)
+
+// UnbanEmoji is the reaction that whitelists the IP of a detected player and lifts the ban.
+const UnbanEmoji = "🔓"
+
+var (
+	// maxDetections is the number of detection notices that can be reacted to
+	maxDetections = 1024
+
+	detectionsMu sync.Mutex
+	detections   = make(map[discord.MessageID]Detection, maxDetections)
+	// insertion order of the remembered notices, the oldest ones are forgotten first
+	detectionOrder = make([]discord.MessageID, 0, maxDetections)
+	// notices whose reaction is being processed
+	claimed = make(map[discord.MessageID]bool)
+)
+
+// Detection is a detected VPN player that a notice refers to.
+type Detection struct {
+	Server  string
+	Player  dto.Player
+	Verdict Verdict
+	// Action is the policy action that was applied, ban or kick
+	Action  string
+	Command string
+	// DryRun is true if the command was not executed
+	DryRun  bool
+	Content string
+}
+
+// ClaimDetection returns the detection of a notice, if it is still remembered and no other
+// reaction to the notice is being processed. The claim ends with ForgetDetection once the
+// reaction was handled or with ReleaseDetection in case it failed.
+func ClaimDetection(messageID discord.MessageID) (Detection, bool) {
+	detectionsMu.Lock()
+	defer detectionsMu.Unlock()
+	detection, found := detections[messageID]
+	if !found || claimed[messageID] {
+		return Detection{}, false
+	}
+	claimed[messageID] = true
+	return detection, true
+}
+
+// ReleaseDetection allows to react to the notice again.
+func ReleaseDetection(messageID discord.MessageID) {
+	detectionsMu.Lock()
+	defer detectionsMu.Unlock()
+	delete(claimed, messageID)
+}
+
+// ForgetDetection prevents further reactions to the notice.
+func ForgetDetection(messageID discord.MessageID) {
+	detectionsMu.Lock()
+	defer detectionsMu.Unlock()
+	delete(detections, messageID)
+	delete(claimed, messageID)
+}
+
+func rememberDetection(messageID discord.MessageID, detection Detection) {
+	detectionsMu.Lock()
+	defer detectionsMu.Unlock()
+
+	if len(detectionOrder) >= maxDetections {
+		delete(detections, detectionOrder[0])
+		detectionOrder = detectionOrder[1:]
+	}
+	detections[messageID] = detection
+	detectionOrder = append(detectionOrder, messageID)
+}
+
+// notify posts the detection to the VPN channel or, if none is configured, to the linked channels.
+func notify(ctx *bot.Context, channelIDs []discord.ChannelID, detection Detection) {
+	if channelID := config.DetectVPN().Channel(); channelID.IsValid() {
+		channelIDs = []discord.ChannelID{channelID}
+	}
+	for _, channelID := range channelIDs {
+		content := fmtDetection(detection, isAdminChannel(channelID))
+		msg, err := ctx.SendMessage(channelID, content, nil)
+		if err != nil {
+			log.Printf("failed to send VPN detection notice: %s\n", err)
+			continue
+		}
+		detection.Content = content
+		rememberDetection(msg.ID, detection)
+
+		err = ctx.React(channelID, msg.ID, UnbanEmoji)
+		if err != nil {
+			log.Printf("failed to add unban reaction: %s\n", err)
+		}
+	}
+}
+
+// isAdminChannel returns true if the IP of the player may be shown in the channel.
+func isAdminChannel(channelID discord.ChannelID) bool {
+	return config.Discord() != nil && config.Discord().IsAdminChannel(channelID)
+}
+
+func fmtDetection(detection Detection, showIP bool) string {
+	player := detection.Player
+	command := detection.Command
+	format := "[VPN]: %s %s %s joined %s and was %s."
+	if detection.DryRun {
+		format = "[VPN DRY RUN]: %s %s %s joined %s and would have been %s."
+	}
+	lines := []string{fmt.Sprintf(
+		format,
+		markdown.Flag(player.Country),
+		markdown.WrapInInlineCodeBlock(player.Name),
+		markdown.WrapInInlineCodeBlock(player.Clan),
+		fmtServer(detection.Server),
+		fmtAction(detection.Action),
+	)}
+
+	verdict := detection.Verdict
+	match := fmt.Sprintf("reason: %s (%s, confidence %.0f%%)", markdown.Escape(verdict.Reason), verdict.Provider, verdict.Confidence*100)
+	if showIP {
+		match = fmt.Sprintf("IP: %s, %s", markdown.WrapInInlineCodeBlock(player.IP), match)
+		if verdict.Range != "" {
+			match += fmt.Sprintf(", matched range: %s", markdown.WrapInInlineCodeBlock(verdict.Range))
+		}
+	} else if player.IP != "" {
+		command = strings.ReplaceAll(command, player.IP, "<IP>")
+	}
+	lines = append(lines, match)
+	lines = append(lines, fmt.Sprintf("%s command: %s", detection.Action, markdown.WrapInInlineCodeBlock(command)))
+	if detection.Action == config.PolicyBan && !detection.DryRun {
+		lines = append(lines, fmt.Sprintf("React with %s to whitelist the IP and lift the ban.", UnbanEmoji))
+	} else {
+		lines = append(lines, fmt.Sprintf("React with %s to whitelist the IP.", UnbanEmoji))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func fmtAction(action string) string {
+	if action == config.PolicyKick {
+		return "kicked"
+	}
+	return "banned"
+}
+
+// fmtServer formats an econ address with its alias, if it has one.
+func fmtServer(econAddr string) string {
+	alias := config.Servers().Alias(econAddr)
+	if alias == econAddr {
+		return econAddr
+	}
+	return fmt.Sprintf("%s (%s)", alias, econAddr)
+}
@@ -0,0 +1,295 @@
+package vpn
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/console"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/ipranges"
+	"github.com/go-redis/redis"
+	"github.com/jxsl13/goripr"
+)
+
+const (
+	verdictCacheKeyPrefix = "vpn_verdict:"
+	// whitelistProvider is the provider of the verdicts of whitelisted IPs
+	whitelistProvider = "whitelist"
+)
+
+var (
+	providersOnce sync.Once
+	providers     []Provider
+)
+
+// Verdict is the result of looking up an IP.
+type Verdict struct {
+	Provider string `json:"provider"`
+	VPN      bool   `json:"vpn"`
+	Reason   string `json:"reason,omitempty"`
+	// Range is the matched range, if the provider knows it
+	Range string `json:"range,omitempty"`
+	// Confidence is within [0, 1]
+	Confidence float64 `json:"confidence"`
+}
+
+// Provider looks up whether an IP belongs to a VPN.
+type Provider interface {
+	Name() string
+	Lookup(ip string) (Verdict, error)
+}
+
+// Providers returns the configured providers in the order in which they are consulted.
+func Providers() []Provider {
+	providersOnce.Do(func() {
+		cfg := config.DetectVPN()
+		for _, name := range cfg.Providers() {
+			switch name {
+			case config.ProviderRanges:
+				providers = append(providers, rangeProvider{})
+			case config.ProviderHTTP:
+				providers = append(providers, cachedProvider{newHTTPProvider(cfg.HTTPProvider())})
+			}
+		}
+	})
+	return providers
+}
+
+// Lookup consults the providers and combines their verdicts according to the configured policy.
+// An error is only returned if none of the providers was able to look up the IP.
+func Lookup(ip string) (Verdict, error) {
+	if net.ParseIP(ip) == nil {
+		return Verdict{}, goripr.ErrInvalidIP
+	}
+
+	if config.DetectVPN().IsWhitelisted(ip) {
+		return Verdict{Provider: whitelistProvider, Confidence: 1}, nil
+	}
+
+	policy := config.DetectVPN().ProviderPolicy()
+	answers := make([]Verdict, 0, len(Providers()))
+	errs := make([]string, 0)
+	for _, provider := range Providers() {
+		verdict, err := provider.Lookup(ip)
+		if err != nil {
+			log.Printf("provider %s failed to look up %s: %s\n", provider.Name(), ip, err)
+			errs = append(errs, fmt.Sprintf("%s: %s", provider.Name(), err))
+			continue
+		}
+		if policy == config.PolicyFirst || (policy == config.PolicyAny && verdict.VPN) {
+			return verdict, nil
+		}
+		answers = append(answers, verdict)
+	}
+	if len(answers) == 0 {
+		return Verdict{}, fmt.Errorf("no provider was able to look up %s: %s", ip, strings.Join(errs, ", "))
+	}
+	if policy == config.PolicyMajority {
+		return majority(answers), nil
+	}
+	// no provider detected a VPN
+	return combine(answers), nil
+}
+
+// majority combines the verdicts of the majority, ties are not considered to be a VPN.
+func majority(verdicts []Verdict) Verdict {
+	positive := make([]Verdict, 0, len(verdicts))
+	negative := make([]Verdict, 0, len(verdicts))
+	for _, verdict := range verdicts {
+		if verdict.VPN {
+			positive = append(positive, verdict)
+		} else {
+			negative = append(negative, verdict)
+		}
+	}
+	if len(positive) > len(negative) {
+		return combine(positive)
+	}
+	return combine(negative)
+}
+
+// combine merges agreeing verdicts, the reason of the first verdict is used
+// and the confidence is averaged.
+func combine(verdicts []Verdict) Verdict {
+	names := make([]string, 0, len(verdicts))
+	result := Verdict{VPN: verdicts[0].VPN}
+	for _, verdict := range verdicts {
+		names = append(names, verdict.Provider)
+		if result.Reason == "" {
+			result.Reason = verdict.Reason
+		}
+		if result.Range == "" {
+			result.Range = verdict.Range
+		}
+		result.Confidence += verdict.Confidence / float64(len(verdicts))
+	}
+	result.Provider = strings.Join(names, ", ")
+	return result
+}
+
+// rangeProvider looks up IPs in the range database of the blacklists and whitelists.
+type rangeProvider struct{}
+
+func (rangeProvider) Name() string {
+	return config.ProviderRanges
+}
+
+func (rp rangeProvider) Lookup(ip string) (Verdict, error) {
+	reason, ipRange, err := config.DetectVPN().RDB().FindSource(ip)
+	if errors.Is(err, goripr.ErrIPNotFound) {
+		return Verdict{Provider: rp.Name(), Confidence: 1}, nil
+	} else if err != nil {
+		return Verdict{}, err
+	}
+	return Verdict{Provider: rp.Name(), VPN: true, Reason: reason, Range: ipRange, Confidence: 1}, nil
+}
+
+// httpProvider looks up IPs via a HTTP JSON API.
+type httpProvider struct {
+	cfg    config.HTTPProviderConfig
+	client *http.Client
+}
+
+func newHTTPProvider(cfg config.HTTPProviderConfig) httpProvider {
+	return httpProvider{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}
+}
+
+func (hp httpProvider) Name() string {
+	return config.ProviderHTTP
+}
+
+func (hp httpProvider) Lookup(ip string) (Verdict, error) {
+	resp, err := hp.client.Get(strings.ReplaceAll(hp.cfg.URL, "{IP}", ip))
+	if err != nil {
+		return Verdict{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Verdict{}, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+
+	var body interface{}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		return Verdict{}, fmt.Errorf("invalid response: %w", err)
+	}
+
+	value, found := jsonField(body, hp.cfg.VerdictField, ip)
+	if !found {
+		return Verdict{}, fmt.Errorf("response does not contain the field %s", hp.cfg.VerdictField)
+	}
+	verdict := Verdict{Provider: hp.Name(), Confidence: 1}
+	for _, positive := range hp.cfg.PositiveValues {
+		if strings.EqualFold(fmt.Sprint(value), positive) {
+			verdict.VPN = true
+			break
+		}
+	}
+
+	if value, found := jsonField(body, hp.cfg.ReasonField, ip); found {
+		// the reason ends up in console commands
+		verdict.Reason = console.Sanitize(fmt.Sprint(value))
+	}
+	if value, found := jsonField(body, hp.cfg.ConfidenceField, ip); found && hp.cfg.ConfidenceMax > 0 {
+		confidence, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+		if err != nil {
+			return Verdict{}, fmt.Errorf("the field %s is not numeric: %v", hp.cfg.ConfidenceField, value)
+		}
+		verdict.Confidence = confidence / hp.cfg.ConfidenceMax
+		if verdict.Confidence > 1 {
+			verdict.Confidence = 1
+		}
+	}
+	return verdict, nil
+}
+
+// jsonField returns the value of the dot separated field path, the variable {IP} is replaced
+// after splitting the path, as IPv4 addresses contain dots themselves.
+func jsonField(body interface{}, path, ip string) (interface{}, bool) {
+	if path == "" {
+		return nil, false
+	}
+	value := body
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		value, ok = object[strings.ReplaceAll(key, "{IP}", ip)]
+		if !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// cachedProvider caches the verdicts of the wrapped provider in redis, errors are not cached.
+type cachedProvider struct {
+	Provider
+}
+
+func (cp cachedProvider) Lookup(ip string) (Verdict, error) {
+	cache := config.DetectVPN().Cache()
+	key := verdictCacheKeyPrefix + cp.Name() + ":" + ip
+
+	data, err := cache.Get(key).Bytes()
+	if err == nil {
+		var verdict Verdict
+		if err = json.Unmarshal(data, &verdict); err == nil {
+			return verdict, nil
+		}
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Printf("failed to read cached verdict of %s: %s\n", ip, err)
+	}
+
+	verdict, err := cp.Provider.Lookup(ip)
+	if err != nil {
+		return verdict, err
+	}
+	data, err = json.Marshal(verdict)
+	if err == nil {
+		err = cache.Set(key, data, config.DetectVPN().CacheTTL()).Err()
+	}
+	if err != nil {
+		log.Printf("failed to cache verdict of %s: %s\n", ip, err)
+	}
+	return verdict, nil
+}
+
+// ForgetVerdicts removes the cached verdicts of all IPs within the range.
+func ForgetVerdicts(ipRange string) error {
+	r, err := ipranges.Parse(ipRange)
+	if err != nil {
+		return err
+	}
+	cache := config.DetectVPN().Cache()
+	keys := make([]string, 0)
+	iter := cache.Scan(0, verdictCacheKeyPrefix+"*", 1000).Iterator()
+	for iter.Next() {
+		key := iter.Val()
+		// vpn_verdict:<provider>:<ip>, IPv6 addresses contain colons themselves
+		parts := strings.SplitN(strings.TrimPrefix(key, verdictCacheKeyPrefix), ":", 2)
+		if len(parts) == 2 && r.Contains(net.ParseIP(parts[1])) {
+			keys = append(keys, key)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return cache.Del(keys...).Err()
+}
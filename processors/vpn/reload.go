@@ -0,0 +1,94 @@
+package vpn
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/service"
+	"github.com/diamondburned/arikawa/v2/bot"
+)
+
+// Watch periodically applies changes of the blacklist and whitelist folders to the range database.
+func Watch(ctx *bot.Context) {
+	interval := config.DetectVPN().ReloadInterval()
+	if interval <= 0 {
+		return
+	}
+	log.Printf("Watching the blacklist and whitelist folders every %s...\n", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-service.Done():
+			log.Println("Stopped watching the blacklist and whitelist folders...")
+			return
+		case <-ticker.C:
+			_, err := Reload(ctx)
+			if err != nil {
+				log.Printf("failed to reload VPN lists: %s\n", err)
+			}
+		}
+	}
+}
+
+// Reload applies changes of the blacklist and whitelist folders and announces them
+// in the VPN channel in case anything changed.
+func Reload(ctx *bot.Context) (config.ImportStats, error) {
+	stats, err := config.DetectVPN().Reload()
+	if err != nil {
+		announce(ctx, fmt.Sprintf("[ERROR]: failed to reload VPN lists: %s", err))
+		return stats, err
+	}
+	if stats.Changed() {
+		announce(ctx, FmtImportStats(stats))
+	}
+	return stats, nil
+}
+
+// announce sends the text to the VPN channel, if one is configured.
+func announce(ctx *bot.Context, text string) {
+	channelID := config.DetectVPN().Channel()
+	if !channelID.IsValid() {
+		return
+	}
+	_, err := ctx.SendMessage(channelID, text, nil)
+	if err != nil {
+		log.Printf("failed to send VPN announcement: %s\n", err)
+	}
+}
+
+// FmtImportStats formats the statistics of a reload of the VPN lists.
+func FmtImportStats(stats config.ImportStats) string {
+	if !stats.Changed() {
+		return fmt.Sprintf("VPN lists are up to date, %d unchanged files.", stats.Unchanged)
+	}
+
+	lines := make([]string, 0, len(stats.Imported)+len(stats.Removed)+1)
+	header := "reloaded VPN lists"
+	if stats.Rebuilt {
+		header += " (database rebuilt)"
+	}
+	lines = append(lines, fmt.Sprintf("%s, %d unchanged files:", header, stats.Unchanged))
+
+	for _, file := range stats.Imported {
+		kind := "blacklist"
+		if file.Whitelist {
+			kind = "whitelist"
+		}
+		line := fmt.Sprintf("%s %s: %d ranges added, %d ranges removed", kind, markdown.WrapInInlineCodeBlock(filepath.Base(file.Path)), file.Added, file.Removed)
+		if file.Invalid > 0 {
+			line += fmt.Sprintf(", %d invalid lines", file.Invalid)
+		}
+		lines = append(lines, line)
+	}
+	for _, path := range stats.Removed {
+		lines = append(lines, fmt.Sprintf("removed file %s", markdown.WrapInInlineCodeBlock(filepath.Base(path))))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/dclog"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/roster"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/vpn"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/service"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// ModerateReaction executes the moderation action of a reaction that a moderator
+// added to a logged message, on the player that the message refers to.
+func (b *Bot) ModerateReaction(e *gateway.MessageReactionAddEvent) {
+	if config.Modules().ErrIfDiscordLoggingDisabled() != nil || e.Member == nil || e.Member.User.Bot {
+		return
+	}
+	action, found := config.Moderation().ReactionAction(e.Emoji)
+	if !found {
+		return
+	}
+	subject, found := dclog.SubjectOf(e.MessageID)
+	if !found || !config.Discord().IsModerator(e.Member) {
+		return
+	}
+
+	econAddr, err := config.NormalizeAddress(subject.Server)
+	if err != nil {
+		econAddr = subject.Server
+	}
+
+	player := subject.Player
+	if entry, found := roster.Player(econAddr, player.ID); found && entry.Name == player.Name {
+		// the roster knows the IP of the player
+		player = entry.Player
+	}
+
+	command, err := config.Moderation().Command(action, player, econAddr, 0, "")
+	if err != nil {
+		b.reply(e.ChannelID, fmt.Sprintf("failed to %s %s: %s", action, player.Name, err))
+		return
+	}
+
+	moderator := e.Member.User.Mention()
+	service.ExecThen(reactionMessage(e), econAddr, command, func(ctx *bot.Context) {
+		// other reactions might have been appended to the message in the meantime
+		content := subject.Content
+		if msg, err := ctx.Message(e.ChannelID, e.MessageID); err == nil {
+			content = msg.Content
+		} else {
+			log.Printf("failed to fetch moderated message: %s\n", err)
+		}
+		content = fmt.Sprintf("%s\n%s %s by %s", content, e.Emoji, action, moderator)
+		_, err := ctx.EditText(e.ChannelID, e.MessageID, content)
+		if err != nil {
+			log.Printf("failed to edit moderated message: %s\n", err)
+		}
+	})
+}
+
+// UnbanReaction whitelists the IP of a player that was banned because of a detected VPN
+// and lifts the ban, once a moderator reacts to the detection notice.
+func (b *Bot) UnbanReaction(e *gateway.MessageReactionAddEvent) {
+	if config.Modules().ErrIfVPNDetectionDisabled() != nil || e.Member == nil || e.Member.User.Bot {
+		return
+	}
+	if e.Emoji.Name != vpn.UnbanEmoji || config.Discord() == nil || !config.Discord().IsModerator(e.Member) {
+		return
+	}
+	detection, found := vpn.ClaimDetection(e.MessageID)
+	if !found {
+		return
+	}
+
+	player := detection.Player
+	requestor := service.Requestor(reactionMessage(e))
+	err := config.DetectVPN().WhitelistRange(player.IP)
+	if err != nil {
+		vpn.ReleaseDetection(e.MessageID)
+		b.reply(e.ChannelID, fmt.Sprintf("failed to whitelist %s: %s", player.Name, err))
+		return
+	}
+	if err = vpn.ForgetVerdicts(player.IP); err != nil {
+		log.Printf("failed to remove cached verdicts of %s: %s\n", player.IP, err)
+	}
+	_, err = vpn.Reload(b.Ctx)
+	if err != nil {
+		log.Printf("failed to reload VPN lists: %s\n", err)
+	}
+	command := ""
+	if !detection.DryRun {
+		// nothing was executed during a dry run
+		command, err = config.DetectVPN().RequestUnban(player, detection.Server, requestor)
+		if err != nil {
+			vpn.ReleaseDetection(e.MessageID)
+			b.reply(e.ChannelID, fmt.Sprintf("failed to unban %s: %s", player.Name, err))
+			return
+		}
+	}
+	vpn.ForgetDetection(e.MessageID)
+
+	content := fmt.Sprintf("%s\n%s whitelisted by %s", detection.Content, e.Emoji, e.Member.User.Mention())
+	if command == "" {
+		service.Audit(b.Ctx, "%s whitelisted %s", requestor, markdown.WrapInInlineCodeBlock(player.Name))
+	} else {
+		service.Audit(b.Ctx, "%s whitelisted %s and executed %s on %s", requestor, markdown.WrapInInlineCodeBlock(player.Name), markdown.WrapInInlineCodeBlock(command), detection.Server)
+		content = fmt.Sprintf("%s\n%s whitelisted and unbanned by %s", detection.Content, e.Emoji, e.Member.User.Mention())
+	}
+	_, err = b.Ctx.EditText(e.ChannelID, e.MessageID, content)
+	if err != nil {
+		log.Printf("failed to edit VPN detection notice: %s\n", err)
+	}
+}
+
+// reactionMessage treats a reaction like a command message of the moderator in the same channel.
+func reactionMessage(e *gateway.MessageReactionAddEvent) gateway.MessageCreateEvent {
+	return gateway.MessageCreateEvent{
+		Message: discord.Message{
+			ChannelID: e.ChannelID,
+			GuildID:   e.GuildID,
+			Author:    e.Member.User,
+		},
+		Member: e.Member,
+	}
+}
+
+func (b *Bot) reply(channelID discord.ChannelID, content string) {
+	_, err := b.Ctx.SendMessage(channelID, content, nil)
+	if err != nil {
+		log.Printf("failed to send message: %s\n", err)
+	}
+}
+
+// ConfirmReaction executes a destructive command once its confirmation prompt
+// receives the confirmation reaction of a moderator.
+func (b *Bot) ConfirmReaction(e *gateway.MessageReactionAddEvent) {
+	if config.Modules().ErrIfDiscordLoggingDisabled() != nil {
+		return
+	}
+	service.Confirm(b.Ctx, e)
+}
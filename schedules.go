@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/service"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// Schedule manages econ commands that are executed periodically.
+type Schedule struct {
+	Ctx *bot.Context
+}
+
+func (s *Schedule) Setup(sub *bot.Subcommand) {
+	sub.Description = "manage periodically executed econ commands"
+}
+
+// Add schedules a command: !schedule add <target|broadcast> <cron expression|@hourly|@daily|...> <command>
+func (s *Schedule) Add(msg *gateway.MessageCreateEvent, args bot.RawArguments) (string, error) {
+	if err := config.Modules().ErrIfSchedulerDisabled(); err != nil {
+		return "", err
+	}
+	if err := errIfNotModerator(msg); err != nil {
+		return "", err
+	}
+
+	target, rest := splitFirst(string(args))
+	cron, command := splitCron(rest)
+	if target == "" || cron == "" || command == "" {
+		return "", fmt.Errorf("usage: !schedule add <target|%s> <minute hour day month weekday|@hourly|@daily> <command>", config.ScheduleTargetBroadcast)
+	}
+	if err := config.Discord().CheckCommandPolicy(command); err != nil {
+		return "", err
+	}
+	if config.Discord().RequiresConfirmation(command) {
+		return "", errors.New("commands that require a confirmation cannot be scheduled")
+	}
+
+	schedule, err := config.Scheduler().AddSchedule(cron, target, command, service.Requestor(*msg))
+	if err != nil {
+		return "", fmt.Errorf("failed to add schedule: %s", err)
+	}
+	return fmt.Sprintf("added schedule %s", fmtSchedule(schedule)), nil
+}
+
+// Remove removes the schedule with the passed ID.
+func (s *Schedule) Remove(msg *gateway.MessageCreateEvent, id int) (string, error) {
+	if err := config.Modules().ErrIfSchedulerDisabled(); err != nil {
+		return "", err
+	}
+	if err := errIfNotModerator(msg); err != nil {
+		return "", err
+	}
+	schedule, err := config.Scheduler().RemoveSchedule(id)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("removed schedule %s", fmtSchedule(schedule)), nil
+}
+
+// List lists all schedules.
+func (s *Schedule) List(msg *gateway.MessageCreateEvent) (string, error) {
+	if err := config.Modules().ErrIfSchedulerDisabled(); err != nil {
+		return "", err
+	}
+	schedules := config.Scheduler().Schedules()
+	if len(schedules) == 0 {
+		return "no schedules defined", nil
+	}
+	lines := make([]string, 0, len(schedules))
+	for _, schedule := range schedules {
+		lines = append(lines, fmtSchedule(schedule))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// splitCron splits the cron expression from the command, the expression is either
+// a single shortcut like @hourly or consists of five whitespace separated fields.
+func splitCron(args string) (cron, command string) {
+	first, rest := splitFirst(args)
+	if strings.HasPrefix(first, "@") {
+		return first, rest
+	}
+
+	fields := []string{first}
+	for len(fields) < 5 && rest != "" {
+		first, rest = splitFirst(rest)
+		fields = append(fields, first)
+	}
+	if len(fields) < 5 {
+		return "", ""
+	}
+	return strings.Join(fields, " "), rest
+}
+
+func fmtSchedule(schedule config.Schedule) string {
+	return fmt.Sprintf(
+		"%d: %s on %s %s (by %s)",
+		schedule.ID,
+		markdown.WrapInInlineCodeBlock(schedule.Cron),
+		schedule.Target,
+		markdown.WrapInInlineCodeBlock(schedule.Command),
+		schedule.Creator,
+	)
+}
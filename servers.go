@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// Alias manages named aliases of econ addresses.
+type Alias struct {
+	Ctx *bot.Context
+}
+
+func (a *Alias) Setup(sub *bot.Subcommand) {
+	sub.Description = "manage named aliases of econ addresses"
+}
+
+// Add creates or updates an alias for the passed econ address.
+func (a *Alias) Add(msg *gateway.MessageCreateEvent, alias, econAddr string) (string, error) {
+	if err := errIfNotModerator(msg); err != nil {
+		return "", err
+	}
+	if err := config.Servers().SetAlias(alias, econAddr); err != nil {
+		return "", fmt.Errorf("failed to add alias %s: %s", alias, err)
+	}
+	return fmt.Sprintf("%s is now an alias of %s", alias, econAddr), nil
+}
+
+// Remove removes an alias that is not used by any group.
+func (a *Alias) Remove(msg *gateway.MessageCreateEvent, alias string) (string, error) {
+	if err := errIfNotModerator(msg); err != nil {
+		return "", err
+	}
+	econAddr, err := config.Servers().RemoveAlias(alias)
+	if err != nil {
+		return "", fmt.Errorf("failed to remove alias %s: %s", alias, err)
+	}
+	return fmt.Sprintf("removed alias %s of %s", alias, econAddr), nil
+}
+
+// List lists all aliases.
+func (a *Alias) List(msg *gateway.MessageCreateEvent) (string, error) {
+	aliases := config.Servers().Aliases()
+	if len(aliases) == 0 {
+		return "no aliases defined", nil
+	}
+	lines := make([]string, 0, len(aliases))
+	for alias, econAddr := range aliases {
+		lines = append(lines, fmt.Sprintf("%s -> %s", alias, econAddr))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
+
+// Group manages named groups of servers.
+type Group struct {
+	Ctx *bot.Context
+}
+
+func (g *Group) Setup(sub *bot.Subcommand) {
+	sub.Description = "manage named groups of server aliases and econ addresses"
+}
+
+// Add adds aliases or econ addresses to a group, the group is created if it does not exist.
+func (g *Group) Add(msg *gateway.MessageCreateEvent, group string, members ...string) (string, error) {
+	if err := errIfNotModerator(msg); err != nil {
+		return "", err
+	}
+	if err := config.Servers().AddToGroup(group, members...); err != nil {
+		return "", fmt.Errorf("failed to add to group %s: %s", group, err)
+	}
+	return fmt.Sprintf("added %s to group %s", strings.Join(members, ", "), group), nil
+}
+
+// Remove removes members from a group or the whole group if no members are passed.
+func (g *Group) Remove(msg *gateway.MessageCreateEvent, group string, members ...string) (string, error) {
+	if err := errIfNotModerator(msg); err != nil {
+		return "", err
+	}
+	if err := config.Servers().RemoveFromGroup(group, members...); err != nil {
+		return "", fmt.Errorf("failed to remove from group %s: %s", group, err)
+	}
+	if len(members) == 0 {
+		return fmt.Sprintf("removed group %s", group), nil
+	}
+	return fmt.Sprintf("removed %s from group %s", strings.Join(members, ", "), group), nil
+}
+
+// List lists all groups and their members.
+func (g *Group) List(msg *gateway.MessageCreateEvent) (string, error) {
+	groups := config.Servers().Groups()
+	if len(groups) == 0 {
+		return "no groups defined", nil
+	}
+	lines := make([]string, 0, len(groups))
+	for group, members := range groups {
+		lines = append(lines, fmt.Sprintf("%s: %s", group, strings.Join(members, ", ")))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n"), nil
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/events"
+)
+
+// Servers do not answer command execution requests. In order to find out which servers received
+// a broadcasted command, a server state request is broadcasted right after the command.
+// Every server consumes its requests in order and answers the state request with a server state
+// event, so a server state event that arrives after the broadcast acknowledges the command.
+
+// ackTimeout is the time the servers have to acknowledge a broadcasted command.
+const ackTimeout = 5 * time.Second
+
+// collectAcks returns the sorted and unique event sources of the server states that are received
+// within the timeout or until the application is closed.
+func collectAcks(states <-chan events.ServerStateEvent, timeout time.Duration) []string {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	unique := make(map[string]bool)
+	for waiting := true; waiting; {
+		select {
+		case state := <-states:
+			unique[state.EventSource] = true
+		case <-timer.C:
+			waiting = false
+		case <-done:
+			waiting = false
+		}
+	}
+
+	result := make([]string, 0, len(unique))
+	for eventSource := range unique {
+		result = append(result, eventSource)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/events"
+)
+
+// acknowledge simulates the server state event of the server.
+func acknowledge(eventSource string) {
+	state := events.NewServerStateEvent()
+	state.EventSource = eventSource
+	receiveServerState(state)
+}
+
+func TestCollectAcks(t *testing.T) {
+	tests := []struct {
+		name   string
+		before []string
+		acks   []string
+		want   []string
+	}{
+		{"none", nil, nil, []string{}},
+		{"single", nil, []string{"127.0.0.1:8303"}, []string{"127.0.0.1:8303"}},
+		{"unique and sorted", nil, []string{"127.0.0.1:8304", "127.0.0.1:8303", "127.0.0.1:8304"}, []string{"127.0.0.1:8303", "127.0.0.1:8304"}},
+		{"before listening", []string{"127.0.0.1:8304"}, []string{"127.0.0.1:8303"}, []string{"127.0.0.1:8303"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done = make(chan struct{})
+			for _, eventSource := range tt.before {
+				acknowledge(eventSource)
+			}
+			acks, stop := listenServerStates()
+			defer stop()
+			for _, eventSource := range tt.acks {
+				acknowledge(eventSource)
+			}
+			if got := collectAcks(acks, 10*time.Millisecond); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collectAcks() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectAcksStopsOnShutdown(t *testing.T) {
+	done = make(chan struct{})
+	acks, stop := listenServerStates()
+	defer stop()
+	acknowledge("127.0.0.1:8303")
+	close(done)
+
+	start := time.Now()
+	collectAcks(acks, time.Minute)
+	if time.Since(start) > time.Second {
+		t.Error("collectAcks() waits for the timeout after the shutdown")
+	}
+}
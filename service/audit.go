@@ -0,0 +1,27 @@
+package service
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/diamondburned/arikawa/v2/bot"
+)
+
+// Audit logs the message and sends it to the audit channel, if one is configured.
+func Audit(ctx *bot.Context, format string, args ...interface{}) {
+	text := fmt.Sprintf(format, args...)
+	log.Printf("[AUDIT]: %s\n", text)
+
+	if config.Discord() == nil {
+		return
+	}
+	channelID := config.Discord().AuditChannel()
+	if !channelID.IsValid() {
+		return
+	}
+	_, err := ctx.SendMessage(channelID, text, nil)
+	if err != nil {
+		log.Printf("failed to send audit message: %s\n", err)
+	}
+}
@@ -1,33 +1,98 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"strings"
 
 	"github.com/Teeworlds-Server-Moderation/common/amqp"
 	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/Teeworlds-Server-Moderation/common/topics"
 	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
 	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/discord"
 	"github.com/diamondburned/arikawa/v2/gateway"
 )
 
+// commandRequest is a command that was issued via discord.
+type commandRequest struct {
+	message gateway.MessageCreateEvent
+	// target is either empty in order to use the econ address that is linked to the
+	// message's channel, topics.Broadcast in order to execute the command on all servers
+	// or a server alias, group or econ address.
+	target  string
+	command string
+	// confirmedBy is the moderator that confirmed a destructive command
+	confirmedBy *discord.User
+	// macro and steps are set in case a macro is executed, the command then contains
+	// all commands of the macro in order to apply the command policies to them.
+	macro string
+	steps []config.MacroStep
+	// executed is optional and called once the command was published to the servers
+	executed func(ctx *bot.Context)
+}
+
 // Execute a specific command
 func Command(message gateway.MessageCreateEvent) {
-	commandChan <- message
+	enqueue(commandRequest{
+		message: message,
+		command: message.Content,
+	})
+}
+
+// Broadcast publishes the command to all servers that are connected to the broker
+// and reports which servers acknowledged it within the acknowledgement timeout.
+func Broadcast(message gateway.MessageCreateEvent, command string) {
+	enqueue(commandRequest{
+		message: message,
+		target:  topics.Broadcast,
+		command: command,
+	})
+}
+
+// Exec executes the command on the servers of the target alias, group or econ address.
+func Exec(message gateway.MessageCreateEvent, target, command string) {
+	enqueue(commandRequest{
+		message: message,
+		target:  target,
+		command: command,
+	})
 }
 
-func commandProcessor(ctx *bot.Context, pub *amqp.Publisher, commands chan gateway.MessageCreateEvent) {
+// ExecThen executes the command like Exec and calls executed once the command was published to
+// the servers. executed is not called if the execution fails or is not confirmed.
+func ExecThen(message gateway.MessageCreateEvent, target, command string, executed func(ctx *bot.Context)) {
+	enqueue(commandRequest{
+		message:  message,
+		target:   target,
+		command:  command,
+		executed: executed,
+	})
+}
+
+// enqueue passes the request to the command processor.
+// Requests are dropped once the application is closed.
+func enqueue(request commandRequest) {
+	select {
+	case <-done:
+	case commandChan <- request:
+	}
+}
+
+func commandProcessor(ctx *bot.Context, pub *amqp.Publisher, commands chan commandRequest) {
 	log.Println("Starting command processor...")
 	for {
 		select {
-		case <-notify:
+		case <-done:
 			// this must be at first, as it's the most important
 			log.Println("Closing command processor subroutine...")
 			return
-		case commandMsg := <-commands:
-			err := processCommand(commandMsg, ctx, pub)
+		case request := <-commands:
+			err := processCommand(request, ctx, pub)
 			if err != nil {
-				reply(ctx, commandMsg, err.Error())
+				reply(ctx, request.message, err.Error())
 			}
 		}
 	}
@@ -37,12 +102,106 @@ func getEconAddr(command gateway.MessageCreateEvent) (string, error) {
 	return config.Discord().GetEconAddr(command.ChannelID)
 }
 
-func processCommand(command gateway.MessageCreateEvent, ctx *bot.Context, pub *amqp.Publisher) error {
-	econAddr, err := getEconAddr(command)
+func processCommand(request commandRequest, ctx *bot.Context, pub *amqp.Publisher) error {
+	if !config.Discord().IsModerator(request.message.Member) {
+		return errors.New("you are not allowed to execute commands")
+	}
+
+	command := strings.Trim(request.command, " \n\r\t")
+	if command == "" {
+		return errors.New("empty command")
+	}
+	if err := config.Discord().CheckCommandPolicy(command); err != nil {
+		return err
+	}
+	if request.confirmedBy == nil && config.Discord().RequiresConfirmation(command) {
+		return requestConfirmation(ctx, request, command)
+	}
+
+	err := execute(request, command, ctx, pub)
 	if err != nil {
 		return err
 	}
+	if request.executed != nil {
+		request.executed(ctx)
+	}
+	return nil
+}
+
+// execute publishes the command to the servers of the request's target.
+func execute(request commandRequest, command string, ctx *bot.Context, pub *amqp.Publisher) error {
 	cmdExecRequest := events.NewRequestCommandExecEvent()
-	cmdExecRequest.Command = strings.Trim(command.Content, " \n\r\t")
-	return pub.Publish("", econAddr, cmdExecRequest)
+	cmdExecRequest.EventSource = QueueName
+	cmdExecRequest.Requestor = Requestor(request.message)
+	cmdExecRequest.Command = command
+	if request.confirmedBy != nil {
+		cmdExecRequest.Requestor = fmt.Sprintf("%s (confirmed by %s#%s)", cmdExecRequest.Requestor, request.confirmedBy.Username, request.confirmedBy.Discriminator)
+	}
+
+	if len(request.steps) > 0 {
+		return processMacro(ctx, pub, request, cmdExecRequest.Requestor)
+	}
+
+	if request.target == topics.Broadcast {
+		return broadcast(request, cmdExecRequest, ctx, pub)
+	}
+
+	if request.target == "" {
+		econAddr, err := getEconAddr(request.message)
+		if err != nil {
+			return err
+		}
+		err = pub.Publish("", config.QueueName(econAddr), cmdExecRequest.Marshal())
+		if err != nil {
+			return err
+		}
+		Audit(ctx, "%s executed %s on %s", cmdExecRequest.Requestor, markdown.WrapInInlineCodeBlock(command), fmtServer(econAddr))
+		return nil
+	}
+
+	econAddrs, err := config.Servers().Resolve(request.target)
+	if err != nil {
+		return err
+	}
+	for _, econAddr := range econAddrs {
+		err = pub.Publish("", config.QueueName(econAddr), cmdExecRequest.Marshal())
+		if err != nil {
+			return fmt.Errorf("failed to execute command on %s: %s", econAddr, err)
+		}
+	}
+	Audit(ctx, "%s executed %s on %s", cmdExecRequest.Requestor, markdown.WrapInInlineCodeBlock(command), strings.Join(econAddrs, ", "))
+	return reply(ctx, request.message, fmtExecSummary(command, econAddrs))
+}
+
+// broadcast publishes the command to all servers followed by a server state request,
+// the answers of the servers acknowledge the command.
+func broadcast(request commandRequest, cmdExecRequest events.RequestCommandExecEvent, ctx *bot.Context, pub *amqp.Publisher) error {
+	states, stop := listenServerStates()
+	err := pub.Publish(topics.Broadcast, "", cmdExecRequest.Marshal())
+	if err != nil {
+		stop()
+		return fmt.Errorf("failed to broadcast command: %s", err)
+	}
+	Audit(ctx, "%s broadcasted %s", cmdExecRequest.Requestor, markdown.WrapInInlineCodeBlock(cmdExecRequest.Command))
+
+	stateRequest := events.NewRequestServerStateEvent()
+	stateRequest.EventSource = QueueName
+	err = pub.Publish(topics.Broadcast, "", stateRequest.Marshal())
+	if err != nil {
+		stop()
+		return fmt.Errorf("broadcasted command, but failed to request the acknowledgements: %s", err)
+	}
+
+	// waiting for the acknowledgements must not block the command processor
+	go func() {
+		defer stop()
+		acked := collectAcks(states, ackTimeout)
+		reply(ctx, request.message, fmtBroadcastSummary(cmdExecRequest.Command, acked, config.Discord().GetEconAddrs()))
+	}()
+	return nil
+}
+
+// Requestor identifies the discord user that requested a command execution.
+func Requestor(message gateway.MessageCreateEvent) string {
+	return fmt.Sprintf("discord:%s#%s", message.Author.Username, message.Author.Discriminator)
 }
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEnqueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		shutdown bool
+		want     int
+	}{
+		{"running", false, 1},
+		{"closed", true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commandChan = make(chan commandRequest, 1)
+			done = make(chan struct{})
+			if tt.shutdown {
+				// a full queue would block forever without the shutdown
+				commandChan <- commandRequest{}
+				close(done)
+			}
+
+			finished := make(chan struct{})
+			go func() {
+				enqueue(commandRequest{command: "status"})
+				close(finished)
+			}()
+			select {
+			case <-finished:
+			case <-time.After(time.Second):
+				t.Fatal("enqueue() blocks")
+			}
+
+			got := 0
+			for len(commandChan) > 0 {
+				if request := <-commandChan; request.command == "status" {
+					got++
+				}
+			}
+			if got != tt.want {
+				t.Errorf("enqueue() queued %d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/discord"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// ConfirmEmoji is the reaction that confirms the execution of a destructive command.
+const ConfirmEmoji = "✅"
+
+var (
+	confirmationsMu sync.Mutex
+	// prompt message -> command request that awaits its confirmation
+	confirmations = make(map[discord.MessageID]commandRequest)
+)
+
+// requestConfirmation asks the author or another moderator to confirm the command
+// by reacting to the prompt within the configured timeout.
+func requestConfirmation(ctx *bot.Context, request commandRequest, command string) error {
+	timeout := config.Discord().ConfirmTimeout()
+	prompt, err := ctx.SendMessage(
+		request.message.ChannelID,
+		fmt.Sprintf(
+			"%s is a destructive command, react with %s within %s to confirm its execution.",
+			markdown.WrapInInlineCodeBlock(command),
+			ConfirmEmoji,
+			timeout,
+		),
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+
+	confirmationsMu.Lock()
+	confirmations[prompt.ID] = request
+	confirmationsMu.Unlock()
+
+	err = ctx.React(prompt.ChannelID, prompt.ID, ConfirmEmoji)
+	if err != nil {
+		log.Printf("failed to add confirmation reaction: %s\n", err)
+	}
+
+	time.AfterFunc(timeout, func() {
+		confirmationsMu.Lock()
+		_, pending := confirmations[prompt.ID]
+		delete(confirmations, prompt.ID)
+		confirmationsMu.Unlock()
+
+		if !pending {
+			return
+		}
+		_, err := ctx.EditText(prompt.ChannelID, prompt.ID, fmt.Sprintf("%s was not confirmed in time.", markdown.WrapInInlineCodeBlock(command)))
+		if err != nil {
+			log.Printf("failed to edit confirmation prompt: %s\n", err)
+		}
+	})
+	return nil
+}
+
+// Confirm executes the pending command of a confirmation prompt, if the reaction
+// was added by a moderator. Reactions of bots are ignored.
+func Confirm(ctx *bot.Context, e *gateway.MessageReactionAddEvent) {
+	if e.Emoji.Name != ConfirmEmoji || e.Member == nil || e.Member.User.Bot {
+		return
+	}
+	if !config.Discord().IsModerator(e.Member) {
+		return
+	}
+
+	confirmationsMu.Lock()
+	request, pending := confirmations[e.MessageID]
+	delete(confirmations, e.MessageID)
+	confirmationsMu.Unlock()
+	if !pending {
+		return
+	}
+
+	confirmer := e.Member.User
+	request.confirmedBy = &confirmer
+	enqueue(request)
+
+	_, err := ctx.EditText(e.ChannelID, e.MessageID, fmt.Sprintf(
+		"%s was confirmed by %s.",
+		markdown.WrapInInlineCodeBlock(request.command),
+		e.Member.User.Mention(),
+	))
+	if err != nil {
+		log.Printf("failed to edit confirmation prompt: %s\n", err)
+	}
+}
@@ -6,6 +6,11 @@ import (
 )
 
 func reply(ctx *bot.Context, original gateway.MessageCreateEvent, replyContent string) error {
+	if !original.Message.ID.IsValid() {
+		// requests that were not caused by a message, e.g. by a reaction
+		_, err := ctx.SendMessage(original.ChannelID, replyContent, nil)
+		return err
+	}
 	_, err := ctx.SendMessageReply(
 		original.ChannelID,
 		replyContent,
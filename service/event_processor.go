@@ -23,7 +23,7 @@ func eventProcessor(ctx *bot.Context, sub *amqp.Subscriber, queue string) {
 
 	for {
 		select {
-		case <-notify:
+		case <-done:
 			// must be as the first case
 			log.Println("Closing event processor subroutine...")
 			return
@@ -33,29 +33,37 @@ func eventProcessor(ctx *bot.Context, sub *amqp.Subscriber, queue string) {
 	}
 }
 
-func getChannelID(msg a.Delivery) (id discord.ChannelID, eventType string, err error) {
-	event := events.BaseEvent{}
-	err = event.Unmarshal(string(msg.Body))
-	if err != nil {
-		return 0, "", err
-	}
-	value, err := config.Discord().GetChannel(event.EventSource)
-	if err != nil {
-		return 0, event.Type, err
+func getChannelIDs(event events.BaseEvent) []discord.ChannelID {
+	if config.Discord() == nil {
+		return nil
 	}
-	return value, event.Type, nil
+	// unlinked servers are still processed, but their events are not routed to any channel
+	ids, _ := config.Discord().GetChannels(event.EventSource, event.Type)
+	return ids
 }
 
 func processEvent(ctx *bot.Context, msg a.Delivery) error {
-	channelID, eventType, err := getChannelID(msg)
+	event := events.BaseEvent{}
+	err := event.Unmarshal(string(msg.Body))
 	if err != nil {
 		return err
 	}
+	if event.Type == events.TypeServerState {
+		state := events.ServerStateEvent{}
+		if err := state.Unmarshal(string(msg.Body)); err == nil {
+			receiveServerState(state)
+		}
+	}
+
+	channelIDs, eventType := getChannelIDs(event), event.Type
 
 	for _, proc := range eventProcessors {
-		err = proc(ctx, channelID, eventType, msg)
+		err = proc(ctx, channelIDs, eventType, msg)
 		if err != nil {
-			ctx.SendMessage(channelID, fmtError(err), nil)
+			log.Printf("failed to process %s event: %s\n", eventType, err)
+			for _, channelID := range channelIDs {
+				ctx.SendMessage(channelID, fmtError(err), nil)
+			}
 		}
 	}
 	return nil
@@ -2,8 +2,67 @@ package service
 
 import (
 	"fmt"
+	"strings"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
 )
 
 func fmtError(err error) string {
 	return fmt.Sprintf("[ERROR]: %s", err)
 }
+
+// fmtServer formats an econ address with its alias, if it has one.
+func fmtServer(econAddr string) string {
+	alias := config.Servers().Alias(econAddr)
+	if alias == econAddr {
+		return econAddr
+	}
+	return fmt.Sprintf("%s (%s)", alias, econAddr)
+}
+
+func fmtServers(econAddrs []string) string {
+	lines := make([]string, 0, len(econAddrs))
+	for _, addr := range econAddrs {
+		lines = append(lines, fmtServer(addr))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fmtBroadcastSummary lists the servers that acknowledged the command and the linked servers
+// that did not acknowledge it within the timeout.
+func fmtBroadcastSummary(command string, ackedAddrs, linkedAddrs []string) string {
+	acked := make(map[string]bool, len(ackedAddrs))
+	for _, addr := range ackedAddrs {
+		acked[addr] = true
+		if normalized, err := config.NormalizeAddress(addr); err == nil {
+			acked[normalized] = true
+		}
+	}
+	missing := make([]string, 0, len(linkedAddrs))
+	for _, addr := range linkedAddrs {
+		if !acked[addr] {
+			missing = append(missing, addr)
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("broadcasted %s", markdown.WrapInInlineCodeBlock(command)))
+	if len(ackedAddrs) == 0 {
+		sb.WriteString(fmt.Sprintf(", no server acknowledged it within %s.", ackTimeout))
+	} else {
+		sb.WriteString(fmt.Sprintf(", acknowledged within %s by:\n%s", ackTimeout, fmtServers(ackedAddrs)))
+	}
+	if len(missing) > 0 {
+		sb.WriteString(fmt.Sprintf("\nno acknowledgement from the linked servers:\n%s", fmtServers(missing)))
+	}
+	return sb.String()
+}
+
+func fmtExecSummary(command string, addrs []string) string {
+	return fmt.Sprintf(
+		"executed %s on:\n%s",
+		markdown.WrapInInlineCodeBlock(command),
+		fmtServers(addrs),
+	)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/amqp"
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// RunMacro executes the expanded steps of a macro in order on the servers of the target alias,
+// group or econ address. The econ address that is linked to the message's channel is used
+// in case the target is empty.
+func RunMacro(message gateway.MessageCreateEvent, target, name string, steps []config.MacroStep) {
+	commands := make([]string, 0, len(steps))
+	for _, step := range steps {
+		if step.Command != "" {
+			commands = append(commands, step.Command)
+		}
+	}
+	enqueue(commandRequest{
+		message: message,
+		target:  target,
+		command: strings.Join(commands, "; "),
+		macro:   name,
+		steps:   steps,
+	})
+}
+
+func processMacro(ctx *bot.Context, pub *amqp.Publisher, request commandRequest, requestor string) error {
+	var econAddrs []string
+	if request.target == "" {
+		econAddr, err := getEconAddr(request.message)
+		if err != nil {
+			return err
+		}
+		econAddrs = []string{econAddr}
+	} else {
+		addrs, err := config.Servers().Resolve(request.target)
+		if err != nil {
+			return err
+		}
+		econAddrs = addrs
+	}
+
+	Audit(ctx, "%s started macro %s on %s", requestor, markdown.WrapInInlineCodeBlock(request.macro), strings.Join(econAddrs, ", "))
+	err := reply(ctx, request.message, fmt.Sprintf("running macro %s on:\n%s", markdown.WrapInInlineCodeBlock(request.macro), fmtServers(econAddrs)))
+	if err != nil {
+		return err
+	}
+
+	// delays must not block the command processor
+	go func() {
+		err := publishSteps(pub, econAddrs, request.steps, requestor)
+		if err != nil {
+			log.Printf("macro %s failed: %s\n", request.macro, err)
+			reply(ctx, request.message, fmt.Sprintf("macro %s failed: %s", markdown.WrapInInlineCodeBlock(request.macro), err))
+			return
+		}
+		Audit(ctx, "macro %s of %s finished", markdown.WrapInInlineCodeBlock(request.macro), requestor)
+	}()
+	return nil
+}
+
+// publishSteps publishes the commands of the steps in order and waits for the delays in between.
+func publishSteps(pub *amqp.Publisher, econAddrs []string, steps []config.MacroStep, requestor string) error {
+	for _, step := range steps {
+		if step.Delay > 0 {
+			timer := time.NewTimer(step.Delay)
+			select {
+			case <-done:
+				timer.Stop()
+				return errors.New("interrupted by the shutdown")
+			case <-timer.C:
+			}
+			continue
+		}
+
+		cmdExecRequest := events.NewRequestCommandExecEvent()
+		cmdExecRequest.EventSource = QueueName
+		cmdExecRequest.Requestor = requestor
+		cmdExecRequest.Command = step.Command
+		for _, econAddr := range econAddrs {
+			err := pub.Publish("", config.QueueName(econAddr), cmdExecRequest.Marshal())
+			if err != nil {
+				return fmt.Errorf("failed to execute %s on %s: %s", step.Command, econAddr, err)
+			}
+		}
+	}
+	return nil
+}
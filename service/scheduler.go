@@ -0,0 +1,83 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/amqp"
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/Teeworlds-Server-Moderation/common/topics"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/diamondburned/arikawa/v2/bot"
+)
+
+// schedulerRequestor identifies command execution requests that were created by the scheduler.
+const schedulerRequestor = "scheduler"
+
+// scheduler executes the due schedules at the beginning of every minute.
+func scheduler(ctx *bot.Context, pub *amqp.Publisher) {
+	log.Println("Starting scheduler...")
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		timer := time.NewTimer(next.Sub(now))
+
+		select {
+		case <-done:
+			timer.Stop()
+			log.Println("Closing scheduler subroutine...")
+			return
+		case <-timer.C:
+			for _, schedule := range config.Scheduler().Due(next) {
+				err := executeSchedule(ctx, pub, schedule)
+				if err != nil {
+					Audit(ctx, "schedule %d failed: %s", schedule.ID, err)
+				}
+			}
+		}
+	}
+}
+
+func executeSchedule(ctx *bot.Context, pub *amqp.Publisher, schedule config.Schedule) error {
+	if config.Discord() != nil {
+		// the policy might have changed since the schedule was created
+		if err := config.Discord().CheckCommandPolicy(schedule.Command); err != nil {
+			return err
+		}
+		// nobody is able to confirm scheduled commands
+		if config.Discord().RequiresConfirmation(schedule.Command) {
+			return errors.New("the command requires a confirmation, which is not possible for scheduled commands")
+		}
+	}
+
+	cmdExecRequest := events.NewRequestCommandExecEvent()
+	cmdExecRequest.EventSource = QueueName
+	cmdExecRequest.Requestor = schedulerRequestor
+	cmdExecRequest.Command = schedule.Command
+
+	if schedule.Target == config.ScheduleTargetBroadcast {
+		err := pub.Publish(topics.Broadcast, "", cmdExecRequest.Marshal())
+		if err != nil {
+			return fmt.Errorf("failed to broadcast command: %s", err)
+		}
+		Audit(ctx, "schedule %d broadcasted %s", schedule.ID, markdown.WrapInInlineCodeBlock(schedule.Command))
+		return nil
+	}
+
+	econAddrs, err := config.Servers().Resolve(schedule.Target)
+	if err != nil {
+		return err
+	}
+	for _, econAddr := range econAddrs {
+		err = pub.Publish("", config.QueueName(econAddr), cmdExecRequest.Marshal())
+		if err != nil {
+			return fmt.Errorf("failed to execute command on %s: %s", econAddr, err)
+		}
+	}
+	Audit(ctx, "schedule %d executed %s on %s", schedule.ID, markdown.WrapInInlineCodeBlock(schedule.Command), strings.Join(econAddrs, ", "))
+	return nil
+}
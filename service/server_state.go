@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/events"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+)
+
+var (
+	statesMu sync.Mutex
+	// every listener receives all server state events
+	stateListeners = make(map[chan events.ServerStateEvent]bool)
+)
+
+// listenServerStates returns a channel that receives all server state events until stop is called.
+func listenServerStates() (states <-chan events.ServerStateEvent, stop func()) {
+	listener := make(chan events.ServerStateEvent, 256)
+	statesMu.Lock()
+	stateListeners[listener] = true
+	statesMu.Unlock()
+
+	return listener, func() {
+		statesMu.Lock()
+		delete(stateListeners, listener)
+		statesMu.Unlock()
+	}
+}
+
+// receiveServerState passes the server state event to all listeners.
+// It never blocks the event processor, events of full listeners are dropped.
+func receiveServerState(state events.ServerStateEvent) {
+	statesMu.Lock()
+	defer statesMu.Unlock()
+	for listener := range stateListeners {
+		select {
+		case listener <- state:
+		default:
+		}
+	}
+}
+
+// RequestServerState requests the state of the server and waits for its answer.
+func RequestServerState(econAddr string, timeout time.Duration) (events.ServerStateEvent, error) {
+	states, stop := listenServerStates()
+	defer stop()
+
+	request := events.NewRequestServerStateEvent()
+	request.EventSource = QueueName
+	err := config.Broker().Publisher().Publish("", config.QueueName(econAddr), request.Marshal())
+	if err != nil {
+		return events.ServerStateEvent{}, fmt.Errorf("failed to request the server state of %s: %s", econAddr, err)
+	}
+	return awaitServerState(states, econAddr, timeout)
+}
+
+// awaitServerState returns the first server state of the server that is received within the timeout.
+func awaitServerState(states <-chan events.ServerStateEvent, econAddr string, timeout time.Duration) (events.ServerStateEvent, error) {
+	if normalized, err := config.NormalizeAddress(econAddr); err == nil {
+		econAddr = normalized
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case state := <-states:
+			source := state.EventSource
+			if normalized, err := config.NormalizeAddress(source); err == nil {
+				source = normalized
+			}
+			if source == econAddr {
+				return state, nil
+			}
+		case <-timer.C:
+			return events.ServerStateEvent{}, fmt.Errorf("%s did not send its server state within %s", econAddr, timeout)
+		case <-done:
+			return events.ServerStateEvent{}, errors.New("interrupted by the shutdown")
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Teeworlds-Server-Moderation/common/dto"
+	"github.com/Teeworlds-Server-Moderation/common/events"
+)
+
+func TestAwaitServerState(t *testing.T) {
+	state := func(eventSource string, players ...dto.Player) events.ServerStateEvent {
+		event := events.NewServerStateEvent()
+		event.EventSource = eventSource
+		event.Players = players
+		return event
+	}
+	player := dto.Player{ID: 3, Name: "tee", IP: "1.2.3.4"}
+
+	tests := []struct {
+		name     string
+		econAddr string
+		states   []events.ServerStateEvent
+		shutdown bool
+		wantErr  bool
+	}{
+		{"answer", "127.0.0.1:8303", []events.ServerStateEvent{state("127.0.0.1:8303", player)}, false, false},
+		{"other servers first", "127.0.0.1:8303", []events.ServerStateEvent{state("127.0.0.1:8304"), state("127.0.0.1:8303", player)}, false, false},
+		{"normalized address", "[::1]:08303", []events.ServerStateEvent{state("[0:0::1]:8303", player)}, false, false},
+		{"no answer", "127.0.0.1:8303", []events.ServerStateEvent{state("127.0.0.1:8304", player)}, false, true},
+		{"shutdown", "127.0.0.1:8303", nil, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done = make(chan struct{})
+			if tt.shutdown {
+				close(done)
+			}
+			states, stop := listenServerStates()
+			defer stop()
+			for _, state := range tt.states {
+				receiveServerState(state)
+			}
+
+			got, err := awaitServerState(states, tt.econAddr, 10*time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("awaitServerState() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (len(got.Players) != 1 || got.Players[0] != player) {
+				t.Errorf("awaitServerState() players = %v, want %v", got.Players, player)
+			}
+		})
+	}
+}
+
+func TestStopListeningServerStates(t *testing.T) {
+	_, stop := listenServerStates()
+	stop()
+	statesMu.Lock()
+	defer statesMu.Unlock()
+	if len(stateListeners) != 0 {
+		t.Errorf("%d listeners remain after stopping", len(stateListeners))
+	}
+}
@@ -4,22 +4,22 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/Teeworlds-Server-Moderation/common/events"
 	"github.com/Teeworlds-Server-Moderation/common/topics"
 	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
 	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors"
 	"github.com/diamondburned/arikawa/v2/bot"
-	"github.com/diamondburned/arikawa/v2/gateway"
 )
 
 var (
 	// commands are put in here
-	commandChan chan gateway.MessageCreateEvent
+	commandChan chan commandRequest
 
 	// notification when application is closed
 	notify chan os.Signal
+	// done is closed once the application is closed
+	done chan struct{}
 
 	initialized = false
 )
@@ -30,21 +30,26 @@ var (
 )
 
 func init() {
-	commandChan = make(chan gateway.MessageCreateEvent, 1024)
+	commandChan = make(chan commandRequest, 1024)
 
 	// cleanup upon application closure
 	notify = make(chan os.Signal, 1)
+	done = make(chan struct{})
 	signal.Notify(notify, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-notify
-		time.Sleep(1 * time.Second)
-
-		// graceful shutdown
-		close(commandChan)
+		// the signal is received only once, all subroutines wait for done instead.
+		// commandChan is never closed, as requests may still be sent by discord handlers.
+		close(done)
 	}()
 	eventProcessors = make([]processors.EventProcessor, 0, 2)
 }
 
+// Done is closed once the application is closed, long running subroutines must stop then.
+func Done() <-chan struct{} {
+	return done
+}
+
 // Starts the srvice
 func Start(ctx *bot.Context) (err error) {
 	if initialized {
@@ -57,6 +62,9 @@ func Start(ctx *bot.Context) (err error) {
 	initQueuesAndExchanges(brokerSub)
 	go eventProcessor(ctx, brokerSub, QueueName)
 	go commandProcessor(ctx, brokerPub, commandChan)
+	if config.Modules().ErrIfSchedulerDisabled() == nil {
+		go scheduler(ctx, brokerPub)
+	}
 
 	initialized = true
 	return nil
@@ -80,9 +88,13 @@ func initQueuesAndExchanges(qcb QueueCreateBinder) {
 		events.TypeVoteKickStarted,
 		events.TypeVoteSpecStarted,
 		events.TypeVoteOptionStarted,
+		// announces the results of votes
+		events.TypeChatServer,
 		events.TypeMapChanged,
 		events.TypePlayerJoined,
 		events.TypePlayerLeft,
+		// acknowledges broadcasted commands
+		events.TypeServerState,
 		topics.Broadcast,
 	)
 }
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/config"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/markdown"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/processors/vpn"
+	"github.com/Teeworlds-Server-Moderation/discord-moderation/service"
+	"github.com/diamondburned/arikawa/v2/bot"
+	"github.com/diamondburned/arikawa/v2/gateway"
+)
+
+// VPN manages the VPN detection.
+type VPN struct {
+	Ctx *bot.Context
+}
+
+func (v *VPN) Setup(sub *bot.Subcommand) {
+	sub.Description = "manage the VPN detection"
+	sub.ChangeCommandInfo("ListSources", "list-sources", "list the blacklist and whitelist files and the remote blacklists")
+}
+
+// errIfVPNCommandForbidden returns an error if the VPN detection is disabled or the author is not a moderator.
+func errIfVPNCommandForbidden(msg *gateway.MessageCreateEvent) error {
+	if err := config.Modules().ErrIfVPNDetectionDisabled(); err != nil {
+		return err
+	}
+	return errIfNotModerator(msg)
+}
+
+// Reload applies changes of the blacklist and whitelist folders immediately.
+func (v *VPN) Reload(msg *gateway.MessageCreateEvent) (string, error) {
+	if err := errIfVPNCommandForbidden(msg); err != nil {
+		return "", err
+	}
+	stats, err := vpn.Reload(v.Ctx)
+	if err != nil {
+		return "", err
+	}
+	return vpn.FmtImportStats(stats), nil
+}
+
+// Check looks up the IP with every provider.
+func (v *VPN) Check(msg *gateway.MessageCreateEvent, ip string) (string, error) {
+	if err := errIfVPNCommandForbidden(msg); err != nil {
+		return "", err
+	}
+	verdict, err := vpn.Lookup(ip)
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{fmt.Sprintf("%s: %s (policy %s)", markdown.WrapInInlineCodeBlock(ip), fmtVerdict(verdict), config.DetectVPN().ProviderPolicy())}
+	for _, provider := range vpn.Providers() {
+		verdict, err := provider.Lookup(ip)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("- %s: %s", provider.Name(), markdown.Escape(err.Error())))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("- %s", fmtVerdict(verdict)))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Add blacklists a range: !vpn add <range> [reason]
+func (v *VPN) Add(msg *gateway.MessageCreateEvent, args bot.ArgumentParts) (string, error) {
+	if err := errIfVPNCommandForbidden(msg); err != nil {
+		return "", err
+	}
+	ipRange, reason := splitRange(args)
+	if ipRange == "" {
+		return "", errors.New("usage: !vpn add <range> [reason]")
+	}
+	err := config.DetectVPN().AddRange(ipRange, reason)
+	if err != nil {
+		return "", err
+	}
+	return v.applyManagedChange(msg, "blacklisted", ipRange)
+}
+
+// Remove removes a range from the blacklist that is managed via discord.
+func (v *VPN) Remove(msg *gateway.MessageCreateEvent, args bot.RawArguments) (string, error) {
+	if err := errIfVPNCommandForbidden(msg); err != nil {
+		return "", err
+	}
+	ipRange := strings.TrimSpace(string(args))
+	if ipRange == "" {
+		return "", errors.New("usage: !vpn remove <range>")
+	}
+	found, err := config.DetectVPN().RemoveRange(ipRange)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("the range %s was not added via discord, use !vpn whitelist to exclude it from other blacklists", ipRange)
+	}
+	return v.applyManagedChange(msg, "removed", ipRange)
+}
+
+// Whitelist excludes a range from all blacklists.
+func (v *VPN) Whitelist(msg *gateway.MessageCreateEvent, args bot.RawArguments) (string, error) {
+	if err := errIfVPNCommandForbidden(msg); err != nil {
+		return "", err
+	}
+	ipRange := strings.TrimSpace(string(args))
+	if ipRange == "" {
+		return "", errors.New("usage: !vpn whitelist <range>")
+	}
+	err := config.DetectVPN().WhitelistRange(ipRange)
+	if err != nil {
+		return "", err
+	}
+	if err = vpn.ForgetVerdicts(ipRange); err != nil {
+		log.Printf("failed to remove cached verdicts of %s: %s\n", ipRange, err)
+	}
+	return v.applyManagedChange(msg, "whitelisted", ipRange)
+}
+
+// ListSources lists the blacklist and whitelist files and the remote blacklists.
+func (v *VPN) ListSources(msg *gateway.MessageCreateEvent) (string, error) {
+	if err := errIfVPNCommandForbidden(msg); err != nil {
+		return "", err
+	}
+	sources, err := config.DetectVPN().ListSources()
+	if err != nil {
+		return "", err
+	}
+	if len(sources) == 0 {
+		return "no blacklists or whitelists found", nil
+	}
+	lines := make([]string, 0, len(sources))
+	for _, source := range sources {
+		lines = append(lines, fmtListSource(source))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Dryrun shows or toggles the dry run, during which detected VPN players are only reported:
+// !vpn dryrun [on|off] [target]
+func (v *VPN) Dryrun(msg *gateway.MessageCreateEvent, args bot.ArgumentParts) (string, error) {
+	if err := errIfVPNCommandForbidden(msg); err != nil {
+		return "", err
+	}
+	if args.Length() == 0 {
+		return fmtDryRun(), nil
+	}
+
+	var enabled bool
+	switch strings.ToLower(args.Arg(0)) {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		return "", errors.New("usage: !vpn dryrun [on|off] [target]")
+	}
+
+	state := "disabled"
+	if enabled {
+		state = "enabled"
+	}
+	target := args.After(1)
+	if target == "" {
+		if err := config.DetectVPN().SetDryRun(enabled); err != nil {
+			return "", err
+		}
+		service.Audit(v.Ctx, "%s %s the VPN dry run on all servers", service.Requestor(*msg), state)
+		return fmt.Sprintf("%s the dry run on all servers", state), nil
+	}
+	if err := config.DetectVPN().SetServerDryRun(target, enabled); err != nil {
+		return "", err
+	}
+	service.Audit(v.Ctx, "%s %s the VPN dry run on %s", service.Requestor(*msg), state, target)
+	return fmt.Sprintf("%s the dry run on %s", state, target), nil
+}
+
+// applyManagedChange imports the modified managed files and audits the change.
+func (v *VPN) applyManagedChange(msg *gateway.MessageCreateEvent, action, ipRange string) (string, error) {
+	service.Audit(v.Ctx, "%s %s %s", service.Requestor(*msg), action, markdown.WrapInInlineCodeBlock(ipRange))
+	_, err := vpn.Reload(v.Ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s", action, markdown.WrapInInlineCodeBlock(ipRange)), nil
+}
+
+// splitRange splits the arguments into a range, which may be <IP> - <IP>, and the remaining text.
+func splitRange(args bot.ArgumentParts) (ipRange, rest string) {
+	if args.Length() == 0 {
+		return "", ""
+	}
+	if args.Length() >= 3 && args.Arg(1) == "-" {
+		return strings.Join(args[:3], " "), strings.Join(args[3:], " ")
+	}
+	return args.Arg(0), strings.Join(args[1:], " ")
+}
+
+func fmtVerdict(verdict vpn.Verdict) string {
+	result := "no VPN"
+	if verdict.VPN {
+		result = "VPN"
+		if verdict.Reason != "" {
+			result += ", " + markdown.Escape(verdict.Reason)
+		}
+	}
+	return fmt.Sprintf("%s (%s, confidence %.0f%%)", result, verdict.Provider, verdict.Confidence*100)
+}
+
+func fmtDryRun() string {
+	enabled, targets := config.DetectVPN().DryRun()
+	lines := make([]string, 0, 2)
+	switch {
+	case enabled:
+		lines = append(lines, "the dry run is enabled on all servers")
+	case len(targets) > 0:
+		lines = append(lines, fmt.Sprintf("the dry run is enabled on: %s", strings.Join(targets, ", ")))
+	default:
+		lines = append(lines, "the dry run is disabled")
+	}
+
+	hits := vpn.DryRunHits()
+	if len(hits) == 0 {
+		return lines[0]
+	}
+	addrs := make([]string, 0, len(hits))
+	for addr := range hits {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	lines = append(lines, "unique IPs of players that would have been punished since the start:")
+	for _, addr := range addrs {
+		lines = append(lines, fmt.Sprintf("%s: %d", config.Servers().Alias(addr), hits[addr]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func fmtListSource(source config.ListSource) string {
+	if source.Path == "" {
+		return fmt.Sprintf("remote blacklist %s: not downloaded yet", markdown.WrapInInlineCodeBlock(source.URL))
+	}
+	kind := "blacklist"
+	if source.Whitelist {
+		kind = "whitelist"
+	}
+	line := fmt.Sprintf("%s %s: %d ranges, modified %s", kind, markdown.WrapInInlineCodeBlock(filepath.Base(source.Path)), source.Ranges, source.Modified.Format("2006-01-02 15:04:05"))
+	if source.URL != "" {
+		line += fmt.Sprintf(", downloaded from %s, last fetched %s", markdown.WrapInInlineCodeBlock(source.URL), source.Fetched.Format("2006-01-02 15:04:05"))
+	}
+	return line
+}